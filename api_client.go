@@ -14,7 +14,13 @@ const (
 )
 
 func (app *Crawler) submitProductData(productData *ProductDetail) error {
-	jsonPayload, err := json.Marshal(productData)
+	// Change tracking fields are internal to the crawler
+	payload := *productData
+	payload.ContentHash = ""
+	payload.FirstSeenAt = nil
+	payload.LastSeenAt = nil
+	payload.LastChangedAt = nil
+	jsonPayload, err := json.Marshal(payload)
 	if err != nil {
 		return fmt.Errorf("json conversion error: %w", err)
 	}
@@ -1,16 +1,19 @@
 package ninjacrawler
 
 import (
+	"context"
 	"fmt"
-	"github.com/go-rod/rod"
 	"github.com/playwright-community/playwright-go"
-	"github.com/temoto/robotstxt"
 	"go.mongodb.org/mongo-driver/mongo"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+	"go.opentelemetry.io/otel/trace/noop"
 	"net/http"
 	"os"
 	"path/filepath"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
@@ -22,36 +25,48 @@ const (
 
 type Crawler struct {
 	*mongo.Client
-	StartTime    time.Time // Start time of the crawler
-	Config       *configService
-	Name         string
-	Url          string
-	BaseUrl      string
-	pw           *playwright.Playwright
-	pwBrowserCtx playwright.BrowserContext
-	//pwPage                 playwright.Page
-	rdBrowser *rod.Browser
-	//rdPage                 *rod.Page
-	UrlSelectors           []UrlSelector
-	ProductDetailSelector  ProductDetailSelector
-	engine                 *Engine
-	Logger                 *defaultLogger
-	httpClient             *http.Client
-	isLocalEnv             bool
-	isStgEnv               bool
-	preference             *AppPreference
-	userAgent              string
-	CurrentProxy           Proxy
-	ReqCount               int32
-	CurrentProxyIndex      int32
-	CurrentCollection      string
-	CurrentUrlCollection   UrlCollection
-	CurrentUrl             string
-	lastWorkingProxyIndex  int32
-	mu                     sync.Mutex
-	CurrentProcessorConfig ProcessorConfig
-	robotsData             *robotstxt.RobotsData
-	requestMetrics         RequestMetrics
+	StartTime             time.Time // Start time of the crawler
+	Config                *configService
+	Name                  string
+	Url                   string
+	BaseUrl               string
+	pw                    *playwright.Playwright
+	browsers              *browserPool // Browsers of dynamic crawls, kept alive across urls
+	UrlSelectors          []UrlSelector
+	ProductDetailSelector ProductDetailSelector
+	siteEngine            *Engine                // Site defaults, never changed by a processor
+	engine                atomic.Pointer[Engine] // Resolved engine of the running processor, read with currentEngine
+	Logger                Logger
+	httpClient            *http.Client
+	isLocalEnv            bool
+	isStgEnv              bool
+	preference            *AppPreference
+	userAgent             string
+	ReqCount              int32
+	CurrentProxyIndex     int32
+	lastWorkingProxyIndex int32
+	shouldRotateProxy     atomic.Bool                     // Set when the current proxy failed, the next proxy pick rotates
+	proxyMu               sync.Mutex                      // Guards proxy selection and rotation
+	activeWorkers         int32                           // Urls in flight in the crawl workers
+	processor             atomic.Pointer[ProcessorConfig] // The running processor, safe to read from any goroutine
+	collectionIndexes     sync.Map                        // CollectionIndex fields per entity collection
+	robots                *robotsCache
+	productChanges        *productChanges
+	structure             *structureMonitor
+	monitor               *monitorPanel
+	metrics               *crawlerMetrics
+	tracer                trace.Tracer
+	tracerProvider        *sdktrace.TracerProvider
+	requestMetrics        RequestMetrics
+	store                 Store
+	rateLimiter           *hostLimiter
+	proxyPool             *ProxyPool
+	proxyBridges          *proxyBridges
+	fetchers              *fetcherRegistry
+	transports            *transportPool
+	interrupted           chan struct{} // Closed on SIGINT or SIGTERM
+	interruptOnce         sync.Once
+	failed                atomic.Bool // Set by Logger.Fatal, a failed run is not resumed as interrupted
 }
 
 func NewCrawler(name, url string, engines ...Engine) *Crawler {
@@ -62,9 +77,15 @@ func NewCrawler(name, url string, engines ...Engine) *Crawler {
 		Name:              name,
 		Url:               url,
 		Config:            config,
-		CurrentProxy:      Proxy{},
 		CurrentProxyIndex: 0,
 		ReqCount:          int32(0),
+		rateLimiter:       newHostLimiter(),
+		proxyPool:         newProxyPool(nil),
+		proxyBridges:      newProxyBridges(),
+		robots:            newRobotsCache(),
+		productChanges:    newProductChanges(),
+		tracer:            noop.NewTracerProvider().Tracer(tracerName),
+		interrupted:       make(chan struct{}),
 	}
 
 	defaultPreference := getDefaultPreference()
@@ -73,16 +94,21 @@ func NewCrawler(name, url string, engines ...Engine) *Crawler {
 		eng := engines[0]
 		crawler.overrideEngineDefaults(&defaultEngine, &eng)
 	}
-	crawler.engine = &defaultEngine
+	crawler.siteEngine = &defaultEngine
+	crawler.engine.Store(crawler.siteEngine)
 	logger := newDefaultLogger(crawler, name)
 	crawler.Logger = logger
-	crawler.Client = crawler.mustGetClient()
+	crawler.metrics = newCrawlerMetrics(crawler)
+	crawler.store = crawler.instrumentStore(crawler.mustGetStore())
 	crawler.BaseUrl = crawler.getBaseUrl(url)
 	crawler.isLocalEnv = config.GetString("APP_ENV") == "local"
 	crawler.isStgEnv = config.GetString("APP_ENV") == "staging"
 	crawler.userAgent = config.GetString("USER_AGENT")
 	crawler.preference = &defaultPreference
 	crawler.lastWorkingProxyIndex = int32(0)
+	crawler.fetchers = newFetcherRegistry(crawler)
+	crawler.browsers = newBrowserPool(crawler)
+	crawler.transports = newTransportPool()
 	return crawler
 }
 
@@ -95,14 +121,24 @@ func (app *Crawler) Start() {
 	app.StartTime = time.Now() // Record start time
 	app.Logger.Summary("Crawler started!")
 	app.bootstrap()
+	if app.isFailed() {
+		return
+	}
+	app.startMonitorPanel()
+	app.startTracing()
+	resuming := app.wasInterrupted()
 	deleteDB := app.Config.GetBool("DELETE_DB")
+	if deleteDB && resuming {
+		app.Logger.Summary("Resuming interrupted run, keeping the database")
+		deleteDB = false
+	}
 	if deleteDB {
 		err := app.dropDatabase()
 		if err != nil {
 			return
 		}
 	}
-	if (app.engine.ForceInstallPlaywright || !app.isLocalEnv) && *app.engine.Adapter == PlayWrightEngine {
+	if (app.currentEngine().ForceInstallPlaywright || !app.isLocalEnv) && *app.currentEngine().Adapter == PlayWrightEngine {
 		app.Logger.Info("Force Installing Playwright!")
 		err := playwright.Install()
 		if err != nil {
@@ -110,13 +146,20 @@ func (app *Crawler) Start() {
 		}
 	}
 	app.syncProxies()
+	app.checkProxies(context.Background())
 	app.newSite()
+	if resuming {
+		app.resumeSite()
+	}
 	app.toggleClient()
+	if app.isFailed() {
+		return
+	}
 	app.startPerformanceTracking() // Start performance tracking
 }
 
 func (app *Crawler) toggleClient() {
-	if *app.engine.IsDynamic {
+	if *app.currentEngine().IsDynamic {
 		pw, err := app.GetPlaywright()
 		if err != nil {
 			app.Logger.Debug("failed to initialize playwright: %v\n", err)
@@ -131,10 +174,11 @@ func (app *Crawler) toggleClient() {
 
 func (app *Crawler) syncProxies() {
 	if app.isLocalEnv {
-		app.engine.ProxyServers = app.getProxyServers()
+		app.siteEngine.ProxyServers = app.getProxyServers()
 	} else {
-		app.engine.ProxyServers = app.getLiveProxyServers()
+		app.siteEngine.ProxyServers = app.getLiveProxyServers()
 	}
+	app.proxyPool.setProxies(app.siteEngine.ProxyServers)
 }
 
 func (app *Crawler) Stop() {
@@ -143,13 +187,20 @@ func (app *Crawler) Stop() {
 			app.HandlePanic(r)
 		}
 	}()
+	app.stopMonitorPanel()
+	app.browsers.close()
 	if app.httpClient != nil {
 		app.httpClient.CloseIdleConnections()
 	}
+	app.transports.closeIdle()
 	if app.pw != nil {
 		app.pw.Stop()
 	}
-	if app.Client != nil {
+	app.proxyBridges.close()
+	if app.store != nil {
+		if app.isInterrupted() && !app.isFailed() {
+			app.markSiteInterrupted()
+		}
 		app.closeClient()
 	}
 	// upload logs
@@ -158,9 +209,10 @@ func (app *Crawler) Stop() {
 		app.UploadLogs()
 	}
 
-	if *app.engine.StoreHtml {
+	if *app.currentEngine().StoreHtml {
 		app.UploadRawHtml()
 	}
+	app.stopTracing()
 	duration := time.Since(app.StartTime)
 	app.Logger.Summary("Crawler completed!")
 	app.Logger.Summary("Crawling duration %v", duration)
@@ -172,74 +224,24 @@ func (app *Crawler) Stop() {
 	}
 }
 
-func (app *Crawler) openBrowsers(proxy Proxy) {
-	var err error
-	if *app.engine.IsDynamic {
-		if *app.engine.Adapter == PlayWrightEngine {
-			app.pw, err = app.GetPlaywright()
-			if err != nil {
-				app.Logger.Debug("failed to initialize playwright: %v\n", err)
-				app.Logger.Fatal("failed to initialize playwright: %v\n", err)
-				return // exit if playwright initialization fails
-			}
-			app.pwBrowserCtx, err = app.GetBrowser(app.pw, app.engine.BrowserType, proxy)
-
-		}
-		if *app.engine.Adapter == RodEngine {
-			app.rdBrowser, err = app.GetRodBrowser(proxy)
-		}
-	} else {
+// ensureHttpClient creates the http client of static crawls, when the site started with a dynamic engine.
+func (app *Crawler) ensureHttpClient() {
+	if app.httpClient == nil {
 		app.httpClient = app.GetHttpClient()
 	}
-	if err != nil {
-		app.Logger.Fatal(err.Error())
-	}
-
 }
-func (app *Crawler) closeBrowsers() {
-	if *app.engine.IsDynamic {
-		if app.pwBrowserCtx != nil {
-			err := app.pwBrowserCtx.Close()
-			if err != nil {
-				app.Logger.Error("Failed to close browser: %v", err)
-				return
-			}
-			peErr := app.pw.Stop()
-			if peErr != nil {
-				app.Logger.Error("Failed to stop playwright: %v", peErr)
-				return
-			}
-		}
-		if app.rdBrowser != nil {
-			app.rdBrowser.Close()
-			if err := app.cleanUpTempFiles(); err != nil {
-				app.Logger.Error("Failed to clean up temp files: %v", err)
-			}
-		}
-	} else {
-		if app.httpClient != nil {
-			app.httpClient.CloseIdleConnections()
-		}
-	}
 
-}
-
-func (app *Crawler) openPages() interface{} {
-	var err error
-	var page interface{}
-	if *app.engine.IsDynamic {
-		if *app.engine.Adapter == PlayWrightEngine {
-			page, err = app.GetPage(app.pwBrowserCtx)
-		}
-		if *app.engine.Adapter == RodEngine {
-			page, err = app.GetRodPage(app.rdBrowser)
-		}
+// openPages returns a fresh page of the browser pool for a dynamic navigation through proxy, nil for a static one.
+func (app *Crawler) openPages(ctx context.Context, proxy Proxy) (interface{}, error) {
+	engine := app.engineFor(ctx)
+	if !*engine.IsDynamic {
+		return nil, nil
 	}
+	page, err := app.browsers.acquire(ctx, engine, proxy)
 	if err != nil {
-		app.Logger.Fatal(err.Error())
+		return nil, fmt.Errorf("could not open a browser page: %w", err)
 	}
-	return page
-
+	return page, nil
 }
 
 func (app *Crawler) UploadLogs() {
@@ -303,25 +305,40 @@ func (app *Crawler) SetPreference(preference AppPreference) *Crawler {
 
 	overridePreferenceDefaults(&defaultPreference, &preference)
 	app.preference = &defaultPreference
+	if app.preference.StructureMonitor != nil {
+		app.structure = newStructureMonitor(*app.preference.StructureMonitor, app.Name)
+	}
 	return app
 }
 
 func (app *Crawler) Handle(handler Handler) {
+	defer app.watchSignals()()
 	defer app.Stop() // Ensure Stop is called after handlers
 	app.Start()
-
-	if handler.UrlHandler != nil {
-		handler.UrlHandler(app)
-	}
-	if handler.ProductHandler != nil {
-		handler.ProductHandler(app)
+	if app.isFailed() {
+		return
 	}
+
+	app.runUntilShutdown(func() {
+		if handler.UrlHandler != nil {
+			handler.UrlHandler(app)
+		}
+		if handler.ProductHandler != nil && !app.isInterrupted() {
+			handler.ProductHandler(app)
+		}
+	})
 }
 func (app *Crawler) AutoHandle(configs []ProcessorConfig) {
+	defer app.watchSignals()()
 	defer app.Stop() // Ensure Stop is called after handlers
 	app.Start()
+	if app.isFailed() {
+		return
+	}
 
-	app.CrawlUrls(configs)
+	app.runUntilShutdown(func() {
+		app.CrawlUrls(configs)
+	})
 }
 func getDefaultPreference() AppPreference {
 	return AppPreference{
@@ -336,6 +353,9 @@ func overridePreferenceDefaults(defaultPreference *AppPreference, preference *Ap
 	if preference.CheckRobotsTxt != nil {
 		defaultPreference.CheckRobotsTxt = preference.CheckRobotsTxt
 	}
+	if preference.StructureMonitor != nil {
+		defaultPreference.StructureMonitor = preference.StructureMonitor
+	}
 }
 
 func getDefaultEngine() Engine {
@@ -388,6 +408,14 @@ func getDefaultEngine() Engine {
 		ApplyRandomSleep:          Bool(false),
 		IsWaitForSelectorOptional: Bool(false),
 		Cookies:                   nil,
+		ConditionalRequests:       Bool(false),
+		ResponseCache:             Bool(false),
+		StickyProxy:               Bool(false),
+		MaxBrowsers:               4,
+		BrowserRecycleAfter:       100,
+		InsecureSkipVerify:        Bool(false),
+		MaxConnsPerHost:           0,
+		MaxIdleConnsPerHost:       10,
 	}
 }
 
@@ -442,6 +470,9 @@ func (app *Crawler) overrideEngineDefaults(defaultEngine *Engine, eng *Engine) {
 	if eng.SleepAfter > 0 {
 		defaultEngine.SleepAfter = eng.SleepAfter
 	}
+	if eng.RateLimit != nil {
+		defaultEngine.RateLimit = eng.RateLimit
+	}
 	if eng.MaxRetryAttempts > 0 {
 		defaultEngine.MaxRetryAttempts = eng.MaxRetryAttempts
 	}
@@ -528,6 +559,27 @@ func (app *Crawler) overrideEngineDefaults(defaultEngine *Engine, eng *Engine) {
 		defaultEngine.ProviderOption.UsePremiumProxyRetry = eng.ProviderOption.UsePremiumProxyRetry
 	}
 	defaultEngine.BlockedURLs = append(defaultEngine.BlockedURLs, eng.BlockedURLs...)
+	if len(eng.Escalation) > 0 {
+		defaultEngine.Escalation = eng.Escalation
+	}
+	if len(eng.ChallengeMarkers) > 0 {
+		defaultEngine.ChallengeMarkers = eng.ChallengeMarkers
+	}
+	if eng.MaxBrowsers > 0 {
+		defaultEngine.MaxBrowsers = eng.MaxBrowsers
+	}
+	if eng.BrowserRecycleAfter > 0 {
+		defaultEngine.BrowserRecycleAfter = eng.BrowserRecycleAfter
+	}
+	if eng.InsecureSkipVerify != nil {
+		defaultEngine.InsecureSkipVerify = eng.InsecureSkipVerify
+	}
+	if eng.MaxConnsPerHost > 0 {
+		defaultEngine.MaxConnsPerHost = eng.MaxConnsPerHost
+	}
+	if eng.MaxIdleConnsPerHost > 0 {
+		defaultEngine.MaxIdleConnsPerHost = eng.MaxIdleConnsPerHost
+	}
 
 	if eng.SleepDuration > 0 {
 		defaultEngine.SleepDuration = eng.SleepDuration
@@ -581,6 +633,15 @@ func (app *Crawler) overrideEngineDefaults(defaultEngine *Engine, eng *Engine) {
 	if eng.Cookies != nil {
 		defaultEngine.Cookies = eng.Cookies
 	}
+	if eng.ConditionalRequests != nil {
+		defaultEngine.ConditionalRequests = eng.ConditionalRequests
+	}
+	if eng.ResponseCache != nil {
+		defaultEngine.ResponseCache = eng.ResponseCache
+	}
+	if eng.StickyProxy != nil {
+		defaultEngine.StickyProxy = eng.StickyProxy
+	}
 }
 
 func (app *Crawler) getLiveProxyServers() []Proxy {
@@ -589,7 +650,7 @@ func (app *Crawler) getLiveProxyServers() []Proxy {
 		app.Logger.Error("Error fetching proxies: %v", err)
 		return nil
 	}
-	return proxies
+	return normalizeProxies(proxies)
 }
 func (app *Crawler) getProxyServers() []Proxy {
 	proxies := app.Config.GetString("PROXY_SERVERS")
@@ -606,7 +667,7 @@ func (app *Crawler) getProxyServers() []Proxy {
 			})
 		}
 	}
-	return proxyServers
+	return normalizeProxies(proxyServers)
 }
 func (app *Crawler) isCpuUsageHigh() bool {
 	//usage, err := cpu.Percent(0, false)
@@ -0,0 +1,319 @@
+package ninjacrawler
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// keySeparator separates the url from the additional index values in a document key.
+const keySeparator = "\x00"
+
+// boltStore is an embedded, file backed implementation of Store.
+// Every collection is a bucket and every document is stored as JSON, keyed by its url.
+// It needs no database server, which makes it suitable for local development and CI.
+type boltStore struct {
+	app *Crawler
+	db  *bolt.DB
+}
+
+func newBoltStore(app *Crawler, path string) (*boltStore, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create db directory: %w", err)
+	}
+	db, err := bolt.Open(path, 0644, &bolt.Options{Timeout: 10 * time.Second})
+	if err != nil {
+		return nil, fmt.Errorf("failed to open bolt db %s: %w", path, err)
+	}
+	return &boltStore{app: app, db: db}, nil
+}
+
+// view runs fn in a read transaction. Bolt calls can not be interrupted, so ctx is only checked before starting.
+func (s *boltStore) view(ctx context.Context, fn func(*bolt.Tx) error) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return s.db.View(fn)
+}
+
+// update runs fn in a read-write transaction, unless ctx is already done.
+func (s *boltStore) update(ctx context.Context, fn func(*bolt.Tx) error) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return s.db.Update(fn)
+}
+
+// documentKey builds the key of a document. Unique collections are keyed by url plus
+// the values of the CollectionIndex fields of the collection, other collections get a sequence suffix.
+func (s *boltStore) documentKey(bucket *bolt.Bucket, collection string, document Map) ([]byte, error) {
+	url, _ := document["url"].(string)
+	key := url
+	if contains(s.app.preference.ExcludeUniqueUrlEntities, collection) {
+		seq, err := bucket.NextSequence()
+		if err != nil {
+			return nil, err
+		}
+		return []byte(fmt.Sprintf("%s%s#%020d", key, keySeparator, seq)), nil
+	}
+	for _, field := range s.app.collectionIndex(collection) {
+		key += keySeparator + fmt.Sprint(document.Get(field))
+	}
+	return []byte(key), nil
+}
+
+// seekUrl returns the first key/value pair stored for url.
+func seekUrl(c *bolt.Cursor, url string) ([]byte, []byte) {
+	prefix := []byte(url)
+	k, v := c.Seek(prefix)
+	if k == nil {
+		return nil, nil
+	}
+	if bytes.Equal(k, prefix) || bytes.HasPrefix(k, append(prefix, keySeparator...)) {
+		return k, v
+	}
+	return nil, nil
+}
+
+func (s *boltStore) insert(ctx context.Context, collection string, documents []interface{}, replace bool) error {
+	return s.update(ctx, func(tx *bolt.Tx) error {
+		bucket, err := tx.CreateBucketIfNotExists([]byte(collection))
+		if err != nil {
+			return err
+		}
+		for _, document := range documents {
+			doc, err := toMap(document)
+			if err != nil {
+				return err
+			}
+			key, err := s.documentKey(bucket, collection, doc)
+			if err != nil {
+				return err
+			}
+			if !replace && bucket.Get(key) != nil {
+				continue // duplicate, mirror the unique index behaviour
+			}
+			value, err := json.Marshal(doc)
+			if err != nil {
+				return err
+			}
+			if err := bucket.Put(key, value); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (s *boltStore) InsertUrlCollections(ctx context.Context, collection string, urlCollections []UrlCollection) error {
+	documents := make([]interface{}, 0, len(urlCollections))
+	for _, urlCollection := range urlCollections {
+		documents = append(documents, urlCollection)
+	}
+	return s.insert(ctx, collection, documents, false)
+}
+
+func (s *boltStore) FindUrlCollections(ctx context.Context, collection string, filter UrlFilter) ([]UrlCollection, error) {
+	var results []UrlCollection
+	err := s.view(ctx, func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte(collection))
+		if bucket == nil {
+			return nil
+		}
+		return bucket.ForEach(func(k, v []byte) error {
+			if filter.Limit > 0 && len(results) >= filter.Limit {
+				return nil
+			}
+			var urlCollection UrlCollection
+			if err := json.Unmarshal(v, &urlCollection); err != nil {
+				return err
+			}
+			if filter.match(urlCollection) {
+				results = append(results, urlCollection)
+			}
+			return nil
+		})
+	})
+	return results, err
+}
+
+func (s *boltStore) FindUrlCollection(ctx context.Context, collection string, url string) (*UrlCollection, error) {
+	var result *UrlCollection
+	err := s.view(ctx, func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte(collection))
+		if bucket == nil {
+			return nil
+		}
+		_, v := seekUrl(bucket.Cursor(), url)
+		if v == nil {
+			return nil
+		}
+		result = &UrlCollection{}
+		return json.Unmarshal(v, result)
+	})
+	if err != nil {
+		return nil, err
+	}
+	if result == nil {
+		return nil, fmt.Errorf("%s not found in %s", url, collection)
+	}
+	return result, nil
+}
+
+func (s *boltStore) UpdateUrlCollection(ctx context.Context, collection string, url string, fields Map) error {
+	return s.update(ctx, func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte(collection))
+		if bucket == nil {
+			return nil
+		}
+		k, v := seekUrl(bucket.Cursor(), url)
+		if v == nil {
+			return nil
+		}
+		var doc Map
+		if err := json.Unmarshal(v, &doc); err != nil {
+			return err
+		}
+		for key, value := range fields {
+			doc[key] = value
+		}
+		value, err := json.Marshal(doc)
+		if err != nil {
+			return err
+		}
+		return bucket.Put(k, value)
+	})
+}
+
+func (s *boltStore) CountUrlCollections(ctx context.Context, collection string, filter UrlFilter) (int, error) {
+	filter.Limit = 0
+	results, err := s.FindUrlCollections(ctx, collection, filter)
+	if err != nil {
+		return 0, err
+	}
+	return len(results), nil
+}
+
+func (s *boltStore) SaveProductDetail(ctx context.Context, collection string, productDetail *ProductDetail) error {
+	return s.insert(ctx, collection, []interface{}{productDetail}, true)
+}
+
+func (s *boltStore) FindProductDetail(ctx context.Context, collection string, url string) (*ProductDetail, error) {
+	var result *ProductDetail
+	err := s.view(ctx, func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte(collection))
+		if bucket == nil {
+			return nil
+		}
+		_, v := seekUrl(bucket.Cursor(), url)
+		if v == nil {
+			return nil
+		}
+		result = &ProductDetail{}
+		return json.Unmarshal(v, result)
+	})
+	if err != nil {
+		return nil, err
+	}
+	if result == nil {
+		return nil, fmt.Errorf("%s not found in %s", url, collection)
+	}
+	return result, nil
+}
+
+func (s *boltStore) FindProductDetails(ctx context.Context, collection string, skip, limit int) ([]ProductDetail, error) {
+	var results []ProductDetail
+	err := s.view(ctx, func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte(collection))
+		if bucket == nil {
+			return nil
+		}
+		c := bucket.Cursor()
+		index := 0
+		for k, v := c.First(); k != nil && len(results) < limit; k, v = c.Next() {
+			index++
+			if index <= skip {
+				continue
+			}
+			var productDetail ProductDetail
+			if err := json.Unmarshal(v, &productDetail); err != nil {
+				return err
+			}
+			results = append(results, productDetail)
+		}
+		return nil
+	})
+	return results, err
+}
+
+func (s *boltStore) InsertSite(ctx context.Context, site SiteCollection) error {
+	return s.insert(ctx, baseCollection, []interface{}{site}, false)
+}
+
+func (s *boltStore) FindSite(ctx context.Context, url string) (*SiteCollection, error) {
+	var result *SiteCollection
+	err := s.view(ctx, func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte(baseCollection))
+		if bucket == nil {
+			return nil
+		}
+		_, v := seekUrl(bucket.Cursor(), url)
+		if v == nil {
+			return nil
+		}
+		result = &SiteCollection{}
+		return json.Unmarshal(v, result)
+	})
+	if err != nil {
+		return nil, err
+	}
+	if result == nil {
+		return nil, fmt.Errorf("%s not found in %s", url, baseCollection)
+	}
+	return result, nil
+}
+
+func (s *boltStore) UpdateSite(ctx context.Context, url string, fields Map) error {
+	return s.UpdateUrlCollection(ctx, baseCollection, url, fields)
+}
+
+func (s *boltStore) Drop(ctx context.Context) error {
+	return s.update(ctx, func(tx *bolt.Tx) error {
+		var names [][]byte
+		if err := tx.ForEach(func(name []byte, _ *bolt.Bucket) error {
+			names = append(names, append([]byte(nil), name...))
+			return nil
+		}); err != nil {
+			return err
+		}
+		for _, name := range names {
+			if err := tx.DeleteBucket(name); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (s *boltStore) Close() error {
+	return s.db.Close()
+}
+
+// toMap converts a document into a Map using its json tags.
+func toMap(document interface{}) (Map, error) {
+	data, err := json.Marshal(document)
+	if err != nil {
+		return nil, err
+	}
+	var doc Map
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+	return doc, nil
+}
@@ -1,52 +1,140 @@
 package ninjacrawler
 
 import (
-	"fmt"
+	"github.com/PuerkitoBio/goquery"
 	"github.com/temoto/robotstxt"
 	"net/http"
+	"net/url"
+	"strings"
+	"sync"
 	"time"
 )
 
+const skipReasonRobotsTxt = "disallowed by robots.txt"
+
+// robotsCache keeps the parsed robots.txt of every host.
+type robotsCache struct {
+	mu    sync.Mutex
+	hosts map[string]*robotsEntry
+}
+
+// robotsEntry is the robots.txt of a host, fetched once. The data is nil when it could not be fetched.
+type robotsEntry struct {
+	once sync.Once
+	data *robotstxt.RobotsData
+}
+
+func newRobotsCache() *robotsCache {
+	return &robotsCache{hosts: make(map[string]*robotsEntry)}
+}
+
+// entry returns the entry of host, creating it on first use.
+func (c *robotsCache) entry(host string) *robotsEntry {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	entry, ok := c.hosts[host]
+	if !ok {
+		entry = &robotsEntry{}
+		c.hosts[host] = entry
+	}
+	return entry
+}
+
 func (app *Crawler) bootstrap() {
-	if app.preference.CheckRobotsTxt != nil && *app.preference.CheckRobotsTxt {
+	if app.robotsEnabled() {
 		app.checkRobotsTxt()
 	}
 }
 
+func (app *Crawler) robotsEnabled() bool {
+	return app.preference.CheckRobotsTxt != nil && *app.preference.CheckRobotsTxt
+}
+
 func (app *Crawler) checkRobotsTxt() {
 	app.Logger.Info("Checking robots.txt")
-	robotsData, isUserAgentAllowed := checkRobotsTxt(app.BaseUrl, app.GetUserAgent())
-	if isUserAgentAllowed {
-		app.robotsData = robotsData
-	} else {
+	robotsData := app.getRobotsData(app.BaseUrl)
+	if robotsData != nil && !robotsData.FindGroup(app.GetUserAgent()).Test("/") {
 		app.Logger.Summary("Crawling is disallowed by robots.txt")
 		app.Logger.Fatal("Crawling is disallowed by robots.txt")
 	}
 }
 
-func checkRobotsTxt(url, userAgent string) (*robotstxt.RobotsData, bool) {
+// getRobotsData returns the robots.txt of the host of rawUrl, fetching it on first use.
+// Only the workers of the same host wait for the fetch, the other hosts are not blocked.
+// The Crawl-delay of the host is applied to the rate limiter when CheckRobotsTxt is enabled.
+func (app *Crawler) getRobotsData(rawUrl string) *robotstxt.RobotsData {
+	parsedURL, err := url.Parse(rawUrl)
+	if err != nil || parsedURL.Host == "" {
+		return nil
+	}
+
+	entry := app.robots.entry(parsedURL.Host)
+	entry.once.Do(func() {
+		entry.data = app.fetchRobotsTxt(parsedURL.Scheme + "://" + parsedURL.Host)
+		if entry.data != nil && app.robotsEnabled() {
+			if delay := entry.data.FindGroup(app.GetUserAgent()).CrawlDelay; delay > 0 {
+				app.Logger.Info("Applying Crawl-delay of %s for %s", delay, parsedURL.Hostname())
+				app.rateLimiter.setCrawlDelay(parsedURL.Hostname(), delay)
+			}
+		}
+	})
+	return entry.data
+}
+
+// isAllowedByRobots reports whether rawUrl may be crawled. It is always true when CheckRobotsTxt is disabled.
+func (app *Crawler) isAllowedByRobots(rawUrl string) bool {
+	if !app.robotsEnabled() {
+		return true
+	}
+	return shouldCrawl(rawUrl, app.getRobotsData(rawUrl), app.GetUserAgent())
+}
+
+// hasNofollowMeta reports whether the page asks robots not to follow its links.
+func (app *Crawler) hasNofollowMeta(doc *goquery.Document) bool {
+	if !app.robotsEnabled() || doc == nil {
+		return false
+	}
+	nofollow := false
+	doc.Find("meta[name]").EachWithBreak(func(i int, s *goquery.Selection) bool {
+		if strings.ToLower(s.AttrOr("name", "")) != "robots" {
+			return true
+		}
+		for _, directive := range strings.Split(strings.ToLower(s.AttrOr("content", "")), ",") {
+			directive = strings.TrimSpace(directive)
+			if directive == "nofollow" || directive == "none" {
+				nofollow = true
+				return false
+			}
+		}
+		return true
+	})
+	return nofollow
+}
+
+// fetchRobotsTxt fetches and parses the robots.txt of baseUrl, nil when it can not be fetched.
+func (app *Crawler) fetchRobotsTxt(baseUrl string) *robotstxt.RobotsData {
 	client := &http.Client{}
-	req, err := http.NewRequest("GET", url+"/robots.txt", nil)
+	req, err := http.NewRequest("GET", baseUrl+"/robots.txt", nil)
 	if err != nil {
-		return nil, true
+		return nil
 	}
-	req.Header.Set("User-Agent", userAgent)
+	req.Header.Set("User-Agent", app.GetUserAgent())
 	client.Timeout = 30 * time.Second
 	response, err := client.Do(req)
-
-	if err != nil || response.StatusCode != http.StatusOK {
-		fmt.Println("Could not fetch robots.txt:", err)
-		return nil, true // default to allow if robots.txt can't be fetched
+	if err != nil {
+		app.Logger.Warn("Could not fetch robots.txt of %s: %v", baseUrl, err)
+		return nil // default to allow if robots.txt can't be fetched
 	}
 	defer response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		app.Logger.Warn("Could not fetch robots.txt of %s: %s", baseUrl, response.Status)
+		return nil
+	}
 
 	robotsData, err := robotstxt.FromResponse(response)
 	if err != nil {
-		fmt.Println("Error parsing robots.txt:", err)
-		return nil, true
+		app.Logger.Error("Error parsing robots.txt of %s: %v", baseUrl, err)
+		return nil
 	}
-
-	// Check if the user-agent is allowed to access the site
-	group := robotsData.FindGroup(userAgent)
-	return robotsData, group.Test("/")
+	return robotsData
 }
@@ -0,0 +1,331 @@
+package ninjacrawler
+
+import (
+	"context"
+	"fmt"
+	"github.com/go-rod/rod"
+	"github.com/playwright-community/playwright-go"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// browserKey identifies the browsers which can serve a page: same adapter, browser type, launch arguments and proxy.
+type browserKey struct {
+	adapter     string
+	browserType string
+	args        string
+	proxy       string
+}
+
+// pooledBrowser is a browser kept alive across urls. Every page is opened in a fresh context of the browser.
+type pooledBrowser struct {
+	key       browserKey
+	pwBrowser playwright.Browser
+	pwOptions playwright.BrowserNewContextOptions
+	rdBrowser *rod.Browser
+	pages     int         // Pages handed out since the launch
+	active    int         // Pages in use
+	retired   bool        // No new pages, closed once the active pages are released
+	crashed   atomic.Bool // Set when the browser disconnected
+	lastUsed  time.Time
+}
+
+// browserLease is a page handed out by the pool.
+type browserLease struct {
+	browser      *pooledBrowser
+	closeContext func() error
+}
+
+// browserPool keeps the browsers of dynamic crawls alive across urls and batches, at most MaxBrowsers of them.
+// A browser is recycled after BrowserRecycleAfter pages and replaced when it crashes.
+type browserPool struct {
+	app       *Crawler
+	mu        sync.Mutex
+	browsers  []*pooledBrowser
+	launching map[browserKey]bool // Browsers being launched, each holding a slot of the pool
+	pwMu      sync.Mutex          // Guards the start of Playwright by concurrent launches
+	leases    map[interface{}]*browserLease
+	released  chan struct{}    // Closed and replaced whenever a browser may have become available
+	removed   []*pooledBrowser // Browsers taken out of the pool, closed by unlock outside p.mu
+}
+
+func newBrowserPool(app *Crawler) *browserPool {
+	return &browserPool{
+		app:       app,
+		launching: make(map[browserKey]bool),
+		leases:    make(map[interface{}]*browserLease),
+		released:  make(chan struct{}),
+	}
+}
+
+// acquire returns a fresh page of a browser of engine going through proxy, launching the browser when needed.
+// When MaxBrowsers are busy, or the browser of proxy is being launched, it waits for a browser until ctx is done.
+func (p *browserPool) acquire(ctx context.Context, engine *Engine, proxy Proxy) (interface{}, error) {
+	key := browserKey{adapter: *engine.Adapter, browserType: engine.BrowserType, args: strings.Join(engine.Args, " "), proxy: proxy.Server}
+	for attempt := 0; ; attempt++ {
+		p.mu.Lock()
+		p.sweep()
+		browser := p.available(key)
+		if browser == nil && !p.launching[key] && p.makeRoom(engine.MaxBrowsers) {
+			// The slot is reserved while the browser launches outside the lock
+			p.launching[key] = true
+			p.unlock()
+			launched, err := p.launch(key, engine, proxy)
+			p.mu.Lock()
+			delete(p.launching, key)
+			p.signal()
+			if err != nil {
+				p.unlock()
+				return nil, err
+			}
+			p.browsers = append(p.browsers, launched)
+			browser = launched
+		}
+		if browser == nil {
+			released := p.released
+			p.unlock()
+			select {
+			case <-released:
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			}
+			continue
+		}
+		browser.active++
+		browser.pages++
+		if engine.BrowserRecycleAfter > 0 && browser.pages >= engine.BrowserRecycleAfter {
+			browser.retired = true
+		}
+		p.unlock()
+
+		lease, page, err := p.newPage(browser, engine)
+		p.mu.Lock()
+		if err != nil {
+			browser.active--
+			browser.crashed.Store(true)
+			p.unlock()
+			if attempt > 0 {
+				return nil, err
+			}
+			p.app.Logger.Warn("Browser %s failed to open a page, relaunching: %v", browser.name(), err)
+			continue
+		}
+		p.leases[page] = lease
+		p.unlock()
+		return page, nil
+	}
+}
+
+// release closes page with its context and hands its browser back to the pool.
+func (p *browserPool) release(page interface{}) {
+	p.mu.Lock()
+	lease, ok := p.leases[page]
+	delete(p.leases, page)
+	p.unlock()
+	if !ok {
+		return
+	}
+
+	if err := lease.closeContext(); err != nil && !lease.browser.alive() {
+		lease.browser.crashed.Store(true)
+	}
+
+	p.mu.Lock()
+	defer p.unlock()
+	lease.browser.active--
+	lease.browser.lastUsed = time.Now()
+	p.sweep()
+	p.signal()
+}
+
+// unlock releases p.mu, then closes the browsers removed from the pool while it was held.
+// Closing a browser waits for its process, which must not block the other requests of the pool.
+func (p *browserPool) unlock() {
+	removed := p.removed
+	p.removed = nil
+	rodInUse := false
+	for _, browser := range p.browsers {
+		rodInUse = rodInUse || browser.rdBrowser != nil
+	}
+	p.mu.Unlock()
+	p.closeBrowsers(removed, rodInUse)
+}
+
+// signal wakes the requests waiting for a browser. It is called with p.mu held.
+func (p *browserPool) signal() {
+	close(p.released)
+	p.released = make(chan struct{})
+}
+
+// close closes every browser, also the ones still in use.
+func (p *browserPool) close() {
+	p.mu.Lock()
+	p.removed = append(p.removed, p.browsers...)
+	p.browsers = nil
+	p.leases = make(map[interface{}]*browserLease)
+	p.unlock()
+}
+
+// available returns the browser of key with room for a page, nil when there is none.
+func (p *browserPool) available(key browserKey) *pooledBrowser {
+	for _, browser := range p.browsers {
+		if browser.key == key && !browser.retired && !browser.crashed.Load() {
+			return browser
+		}
+	}
+	return nil
+}
+
+// makeRoom reports whether a browser can be launched, removing the least recently used idle browser when the pool is full.
+// Browsers being launched count towards maxBrowsers.
+func (p *browserPool) makeRoom(maxBrowsers int) bool {
+	if len(p.browsers)+len(p.launching) < maxBrowsers {
+		return true
+	}
+	var idle *pooledBrowser
+	for _, browser := range p.browsers {
+		if browser.active == 0 && (idle == nil || browser.lastUsed.Before(idle.lastUsed)) {
+			idle = browser
+		}
+	}
+	if idle == nil {
+		return false
+	}
+	p.remove(idle)
+	return true
+}
+
+// sweep removes the retired and crashed browsers which have no page in use.
+func (p *browserPool) sweep() {
+	for _, browser := range append([]*pooledBrowser(nil), p.browsers...) {
+		if browser.active > 0 {
+			continue
+		}
+		if browser.crashed.Load() {
+			p.app.Logger.Warn("Browser %s crashed, replacing it", browser.name())
+			p.remove(browser)
+		} else if browser.retired {
+			p.app.Logger.Debug("Recycling browser %s after %d pages", browser.name(), browser.pages)
+			p.remove(browser)
+		}
+	}
+}
+
+// remove takes browser out of the pool. It is called with p.mu held, the browser is closed by unlock.
+func (p *browserPool) remove(browser *pooledBrowser) {
+	for i, b := range p.browsers {
+		if b == browser {
+			p.browsers = append(p.browsers[:i], p.browsers[i+1:]...)
+			break
+		}
+	}
+	p.removed = append(p.removed, browser)
+}
+
+// launch starts a browser of key for engine going through proxy. It is called without p.mu, the caller adds the browser to the pool.
+func (p *browserPool) launch(key browserKey, engine *Engine, proxy Proxy) (*pooledBrowser, error) {
+	browser := &pooledBrowser{key: key, lastUsed: time.Now()}
+	if key.adapter == RodEngine {
+		rdBrowser, err := p.app.launchRodBrowser(engine, proxy)
+		if err != nil {
+			return nil, err
+		}
+		browser.rdBrowser = rdBrowser
+	} else {
+		pw, err := p.playwright()
+		if err != nil {
+			return nil, err
+		}
+		pwBrowser, contextOpts, err := p.app.launchPlaywrightBrowser(pw, engine, proxy)
+		if err != nil {
+			return nil, err
+		}
+		browser.pwBrowser = pwBrowser
+		browser.pwOptions = contextOpts
+		pwBrowser.OnDisconnected(func(playwright.Browser) {
+			browser.crashed.Store(true)
+		})
+	}
+	p.app.metrics.openBrowsers.Inc()
+	return browser, nil
+}
+
+// playwright returns the Playwright instance of the crawler, starting it on first use.
+func (p *browserPool) playwright() (*playwright.Playwright, error) {
+	p.pwMu.Lock()
+	defer p.pwMu.Unlock()
+	if p.app.pw == nil {
+		pw, err := p.app.GetPlaywright()
+		if err != nil {
+			return nil, fmt.Errorf("failed to initialize playwright: %w", err)
+		}
+		p.app.pw = pw
+	}
+	return p.app.pw, nil
+}
+
+// closeBrowsers closes browsers, cleaning up the user data of Rod unless rodInUse reports other Rod browsers in the pool.
+// It is called without p.mu.
+func (p *browserPool) closeBrowsers(browsers []*pooledBrowser, rodInUse bool) {
+	closedRod := false
+	for _, browser := range browsers {
+		p.app.metrics.openBrowsers.Dec()
+		if browser.rdBrowser != nil {
+			_ = browser.rdBrowser.Close()
+			closedRod = true
+			continue
+		}
+		if err := browser.pwBrowser.Close(); err != nil && !browser.crashed.Load() {
+			p.app.Logger.Error("Failed to close browser: %v", err)
+		}
+	}
+	if closedRod && !rodInUse {
+		if err := p.app.cleanUpTempFiles(); err != nil {
+			p.app.Logger.Error("Failed to clean up temp files: %v", err)
+		}
+	}
+}
+
+// newPage opens a page of engine in a fresh context of browser.
+func (p *browserPool) newPage(browser *pooledBrowser, engine *Engine) (*browserLease, interface{}, error) {
+	if browser.rdBrowser != nil {
+		incognito, err := browser.rdBrowser.Incognito()
+		if err != nil {
+			return nil, nil, err
+		}
+		page, err := p.app.GetRodPage(incognito)
+		if err != nil {
+			_ = incognito.Close()
+			return nil, nil, err
+		}
+		return &browserLease{browser: browser, closeContext: incognito.Close}, page, nil
+	}
+	browserCtx, err := p.app.newPlaywrightContext(browser.pwBrowser, browser.pwOptions, engine)
+	if err != nil {
+		return nil, nil, err
+	}
+	page, err := p.app.getPage(browserCtx, engine)
+	if err != nil {
+		_ = browserCtx.Close()
+		return nil, nil, err
+	}
+	return &browserLease{browser: browser, closeContext: func() error { return browserCtx.Close() }}, page, nil
+}
+
+// alive reports whether the browser still responds.
+func (b *pooledBrowser) alive() bool {
+	if b.rdBrowser != nil {
+		_, err := b.rdBrowser.Version()
+		return err == nil
+	}
+	return b.pwBrowser.IsConnected()
+}
+
+func (b *pooledBrowser) name() string {
+	if b.key.proxy == "" {
+		return b.key.browserType
+	}
+	return fmt.Sprintf("%s via %s", b.key.browserType, b.key.proxy)
+}
@@ -42,7 +42,7 @@ func UploadToGCPBucket(dirName, GCP_CREDENTIALS_PATH, bucketName, sourceFileName
 	}
 	defer func() {
 		if err := client.Close(); err != nil {
-			fmt.Println("Failed to close storage client: %v", err)
+			fmt.Printf("Failed to close storage client: %v\n", err)
 		}
 	}()
 
@@ -52,7 +52,7 @@ func UploadToGCPBucket(dirName, GCP_CREDENTIALS_PATH, bucketName, sourceFileName
 	}
 	defer func() {
 		if err := file.Close(); err != nil {
-			fmt.Println("Failed to close file %s: %v", sourceFileName, err)
+			fmt.Printf("Failed to close file %s: %v\n", sourceFileName, err)
 		}
 	}()
 
@@ -61,7 +61,7 @@ func UploadToGCPBucket(dirName, GCP_CREDENTIALS_PATH, bucketName, sourceFileName
 
 	contentType, err := detectContentType(sourceFileName)
 	if err != nil {
-		fmt.Println("Failed to detect content type for file %s: %v", sourceFileName, err)
+		fmt.Printf("Failed to detect content type for file %s: %v\n", sourceFileName, err)
 		writer.ContentType = "application/octet-stream" // Default to binary stream if detection fails
 	} else {
 		writer.ContentType = contentType
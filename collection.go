@@ -3,25 +3,31 @@ package ninjacrawler
 import "time"
 
 type SiteCollection struct {
-	Url       string     `json:"url" bson:"url"`
-	BaseUrl   string     `json:"base_url" bson:"base_url"`
-	Pid       bool       `json:"pid" bson:"pid"`
-	Status    bool       `json:"status" bson:"status"`
-	Attempts  int        `json:"attempts" bson:"attempts"`
-	StartedAt time.Time  `json:"started_at" bson:"started_at"`
-	EndedAt   *time.Time `json:"ended_at" bson:"ended_at"`
+	Url         string     `json:"url" bson:"url"`
+	BaseUrl     string     `json:"base_url" bson:"base_url"`
+	Pid         bool       `json:"pid" bson:"pid"`
+	Status      bool       `json:"status" bson:"status"`
+	Attempts    int        `json:"attempts" bson:"attempts"`
+	Interrupted bool       `json:"interrupted" bson:"interrupted"` // The last run was stopped by a termination signal
+	StartedAt   time.Time  `json:"started_at" bson:"started_at"`
+	EndedAt     *time.Time `json:"ended_at" bson:"ended_at"`
 }
 type UrlCollection struct {
 	Url            string                 `json:"url" bson:"url"`
 	Parent         string                 `json:"parent" bson:"parent"`
 	ApiUrl         string                 `json:"api_url" bson:"api_url"`
 	CurrentPageUrl string                 `json:"current_page_url" bson:"current_page_url"`
+	SessionKey     string                 `json:"session_key,omitempty" bson:"session_key,omitempty"` // Binds the url and its children to one proxy with StickyProxy, the parent url when empty
 	Status         bool                   `json:"status" bson:"status"`
 	Error          bool                   `json:"error" bson:"error"`
 	StatusCode     int                    `json:"status_code" bson:"status_code"`
 	Attempts       int                    `json:"attempts" bson:"attempts"`
 	MetaData       map[string]interface{} `json:"meta_data" bson:"meta_data"`
 	ErrorLog       string                 `json:"error_log" bson:"error_log"`
+	SkipReason     string                 `json:"skip_reason,omitempty" bson:"skip_reason,omitempty"`
+	ETag           string                 `json:"etag,omitempty" bson:"etag,omitempty"`
+	LastModified   string                 `json:"last_modified,omitempty" bson:"last_modified,omitempty"`
+	FetchTier      string                 `json:"fetch_tier,omitempty" bson:"fetch_tier,omitempty"` // Escalation tier which fetched the url last time
 	CreatedAt      time.Time              `json:"created_at" bson:"created_at"`
 	UpdatedAt      *time.Time             `json:"updated_at" bson:"updated_at"`
 }
@@ -47,6 +53,10 @@ type ProductDetail struct {
 	ListPrice        string          `json:"list_price" bson:"list_price"`
 	SellingPrice     string          `json:"selling_price" bson:"selling_price"`
 	Attributes       []AttributeItem `json:"attributes" bson:"attributes"`
+	ContentHash      string          `json:"content_hash,omitempty" bson:"content_hash,omitempty" csv:"-"`
+	FirstSeenAt      *time.Time      `json:"first_seen_at,omitempty" bson:"first_seen_at,omitempty" csv:"-"`
+	LastSeenAt       *time.Time      `json:"last_seen_at,omitempty" bson:"last_seen_at,omitempty" csv:"-"`
+	LastChangedAt    *time.Time      `json:"last_changed_at,omitempty" bson:"last_changed_at,omitempty" csv:"-"`
 }
 type AttributeItem struct {
 	Key   string `json:"key" bson:"key"`
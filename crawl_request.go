@@ -0,0 +1,38 @@
+package ninjacrawler
+
+import "context"
+
+// crawlRequest is the state of a single navigation. It travels in the context of the navigation,
+// so concurrent workers never read each other's collection, url or proxy.
+type crawlRequest struct {
+	Collection  string // Collection of the url collection being crawled
+	Url         string // Url being crawled, the final url after a redirection
+	DocumentUrl string // Url of the url collection being crawled, Url is its ApiUrl or CurrentPageUrl when set
+	Proxy       Proxy
+	Provider    string  // Provider of the tier which fetched the page, set when the fetch escalated
+	Engine      *Engine // Engine of the navigation, nil for the engine of the running processor
+	Session     string  // Sticky proxy session of the url collection being crawled
+}
+
+type crawlRequestKey struct{}
+
+// withCrawlRequest returns a copy of ctx carrying req.
+func withCrawlRequest(ctx context.Context, req *crawlRequest) context.Context {
+	return context.WithValue(ctx, crawlRequestKey{}, req)
+}
+
+// crawlRequestFrom returns the request carried by ctx, an empty request when there is none.
+func crawlRequestFrom(ctx context.Context) *crawlRequest {
+	if req, ok := ctx.Value(crawlRequestKey{}).(*crawlRequest); ok {
+		return req
+	}
+	return &crawlRequest{}
+}
+
+// documentUrl returns the url which keys the url collection document of the request, fallback outside of a crawl.
+func (r *crawlRequest) documentUrl(fallback string) string {
+	if r.DocumentUrl != "" {
+		return r.DocumentUrl
+	}
+	return fallback
+}
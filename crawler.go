@@ -4,68 +4,44 @@ import (
 	"context"
 	"errors"
 	"github.com/PuerkitoBio/goquery"
+	"github.com/go-rod/rod"
 	"github.com/playwright-community/playwright-go"
-	"strings"
-	"sync"
 	"sync/atomic"
 	"time"
 )
 
-var activeGoroutines int32 // Tracks how many goroutines are currently active
-var proxyMutex sync.Mutex  // Ensures exclusive access when rotating proxies
-
 func (app *Crawler) crawlWorker(ctx context.Context, processorConfig ProcessorConfig, urlChan <-chan UrlCollection, resultChan chan<- interface{}, isLocalEnv bool, counter *int32, currentProxyIndex *int32) {
-	var page playwright.Page
-	var browser playwright.Browser
+	var page interface{}
 	var err error
 	var doc *goquery.Document
 	var apiResponse map[string]interface{}
 
 	// Rotate proxy in ascending order (round-robin)
 	rotateProxy := func() Proxy {
-		proxyMutex.Lock()
-		defer proxyMutex.Unlock()
+		app.proxyMu.Lock()
+		defer app.proxyMu.Unlock()
 
 		// Ensure the proxies rotate in ascending order, wrapping around to the first one after the last
 		newIndex := atomic.AddInt32(currentProxyIndex, 1)
-		if newIndex >= int32(len(app.engine.ProxyServers)) {
+		if newIndex >= int32(len(app.currentEngine().ProxyServers)) {
 			atomic.StoreInt32(currentProxyIndex, 0) // Reset to the first proxy
 			newIndex = 0
 		}
 
 		// Select the proxy based on the updated index
-		proxy := app.engine.ProxyServers[newIndex]
-		app.CurrentProxy = proxy
-
+		proxy := app.currentEngine().ProxyServers[newIndex]
 		app.Logger.Debug("Rotating proxy to %s", proxy.Server)
 
-		// Initialize the browser with the new proxy if dynamic crawling is used
-		if *app.engine.IsDynamic {
-			browser, page, err = app.GetBrowserPage(app.pw, app.engine.BrowserType, proxy)
-			if err != nil {
-				app.Logger.Fatal(err.Error())
-			}
-		}
-
 		return proxy
 	}
 	currentProxy := Proxy{}
 	// Set the initial proxy (start from the first proxy)
-	if len(app.engine.ProxyServers) > 0 {
-		currentProxy = app.engine.ProxyServers[*currentProxyIndex]
+	if len(app.currentEngine().ProxyServers) > 0 {
+		currentProxy = app.currentEngine().ProxyServers[*currentProxyIndex]
 	}
-	app.CurrentProxy = currentProxy
 
-	if *app.engine.IsDynamic {
-		browser, page, err = app.GetBrowserPage(app.pw, app.engine.BrowserType, currentProxy)
-		if err != nil {
-			app.Logger.Fatal(err.Error())
-		}
-		defer browser.Close()
-		defer page.Close()
-	}
-
-	operationCount := 0 // Initialize operation count
+	// The page of every url comes from the browser pool, it is handed back when the next url arrives
+	defer func() { app.closePages(page) }()
 
 	for {
 		select {
@@ -77,17 +53,18 @@ func (app *Crawler) crawlWorker(ctx context.Context, processorConfig ProcessorCo
 			}
 			return
 		case urlCollection, more := <-urlChan:
-			if !more {
-				return
+			if !more || app.isInterrupted() {
+				return // The url stays pending on shutdown
 			}
-			app.CurrentUrlCollection = urlCollection
-			if app.engine.RetrySleepDuration > 0 && inArray(app.engine.ErrorCodes, urlCollection.StatusCode) {
+			app.closePages(page)
+			page = nil
+			if app.currentEngine().RetrySleepDuration > 0 && inArray(app.currentEngine().ErrorCodes, urlCollection.StatusCode) {
 				app.HandleThrottling(urlCollection.Attempts, urlCollection.StatusCode)
 			}
-			atomic.AddInt32(&activeGoroutines, 1) // Increment the active goroutine counter
+			atomic.AddInt32(&app.activeWorkers, 1) // Increment the active goroutine counter
 
 			// Rotate proxy on receiving specific error codes
-			//if app.engine.ProxyStrategy == ProxyStrategyRotation && inArray(app.engine.ErrorCodes, urlCollection.StatusCode) {
+			//if app.currentEngine().ProxyStrategy == ProxyStrategyRotation && inArray(app.currentEngine().ErrorCodes, urlCollection.StatusCode) {
 			//	currentProxy = rotateProxy()
 			//}
 			preHandlerError := false
@@ -109,40 +86,71 @@ func (app *Crawler) crawlWorker(ctx context.Context, processorConfig ProcessorCo
 			if urlCollection.CurrentPageUrl != "" {
 				crawlableUrl = urlCollection.CurrentPageUrl
 			}
+			if !app.isAllowedByRobots(crawlableUrl) {
+				app.Logger.Warn("[SKIP] %s: %s", skipReasonRobotsTxt, crawlableUrl)
+				if markErr := app.markAsSkipped(ctx, urlCollection.Url, processorConfig.OriginCollection, skipReasonRobotsTxt); markErr != nil {
+					app.Logger.Error(markErr.Error())
+				}
+				continue
+			}
 
-			if currentProxy.Server != "" {
-				app.Logger.Info("Crawling :%s: %s using Proxy %s", processorConfig.OriginCollection, crawlableUrl, currentProxy.Server)
+			proxy := app.sessionProxy(ctx, urlCollection, currentProxy)
+			if proxy.Server != "" {
+				app.Logger.Info("Crawling :%s: %s using Proxy %s", processorConfig.OriginCollection, crawlableUrl, proxy.Server)
 			} else {
 				app.Logger.Info("Crawling :%s: %s", processorConfig.OriginCollection, crawlableUrl)
 			}
-			if *app.engine.IsDynamic {
-				_, doc, err = app.NavigateToURL(page, crawlableUrl, currentProxy)
-			} else {
-				switch processorConfig.Processor.(type) {
-				case ProductDetailApi:
-					apiResponse, err = app.NavigateToApiURL(app.httpClient, crawlableUrl, currentProxy)
-				default:
-					doc, err = app.NavigateToStaticURL(app.httpClient, crawlableUrl, currentProxy)
+			start := time.Now()
+			reqCtx := withCrawlRequest(ctx, &crawlRequest{Collection: processorConfig.OriginCollection, Url: crawlableUrl, DocumentUrl: urlCollection.Url, Proxy: proxy})
+			// The page goes through the proxy of the session, the one credited with the navigation
+			if page, err = app.openPages(reqCtx, proxy); err != nil {
+				if ctx.Err() == nil {
+					app.Logger.Fatal("%v", err)
 				}
+				return
+			}
+			pwPage, _ := page.(playwright.Page)
+			rodPage, _ := page.(*rod.Page)
+			_, navigateToApi := processorConfig.Processor.(ProductDetailApi)
+			if rodPage != nil {
+				_, doc, err = app.navigateRodURL(reqCtx, rodPage, crawlableUrl, proxy)
+			} else if pwPage != nil {
+				_, doc, err = app.navigateToURL(reqCtx, pwPage, crawlableUrl, proxy)
+			} else if navigateToApi {
+				apiResponse, err = app.navigateToApiURL(reqCtx, app.httpClient, crawlableUrl, proxy)
+			} else {
+				doc, err = app.navigateToStaticURL(reqCtx, app.httpClient, crawlableUrl, proxy)
 			}
+			app.observeNavigation(processorConfig.OriginCollection, fetchProvider(app.engineFor(reqCtx), navigateToApi), proxy, start, err)
 
+			var notModifiedErr *NotModifiedError
+			if errors.As(err, &notModifiedErr) {
+				app.Logger.Info("Unchanged since last crawl: %s", urlCollection.Url)
+				if isProductProcessor(processorConfig) {
+					app.markProductUnchanged(ctx, processorConfig.Entity, urlCollection.Url)
+				}
+				if markErr := app.markAsComplete(ctx, urlCollection.Url, processorConfig.OriginCollection); markErr != nil {
+					app.Logger.Error(markErr.Error())
+				}
+				continue
+			}
 			if err != nil {
-				if strings.Contains(err.Error(), "StatusCode:404") {
-					if markMaxErr := app.MarkAsMaxErrorAttempt(urlCollection.Url, processorConfig.OriginCollection, err.Error()); markMaxErr != nil {
+				if IsNotFound(err) {
+					if markMaxErr := app.MarkAsMaxErrorAttemptContext(ctx, urlCollection.Url, processorConfig.OriginCollection, err.Error()); markMaxErr != nil {
 						app.Logger.Error("markMaxErr: ", markMaxErr.Error())
 						return
 					}
-				} else if strings.Contains(err.Error(), "isRetryable") && atomic.AddInt32(&activeGoroutines, -1) == 0 {
-					if app.engine.ProxyStrategy == ProxyStrategyRotation {
+				} else if IsRetryable(err) && atomic.AddInt32(&app.activeWorkers, -1) == 0 {
+					if app.currentEngine().ProxyStrategy == ProxyStrategyRotation {
 						// Rotate the proxy on receiving a 403
 						currentProxy = rotateProxy()
 					}
-					if markErr := app.MarkAsError(urlCollection.Url, processorConfig.OriginCollection, err.Error()); markErr != nil {
+					if markErr := app.MarkAsErrorContext(ctx, urlCollection.Url, processorConfig.OriginCollection, err.Error()); markErr != nil {
 						app.Logger.Error("markErr: ", markErr.Error())
 						return
 					}
 				} else {
-					if markErr := app.MarkAsError(urlCollection.Url, processorConfig.OriginCollection, err.Error()); markErr != nil {
+					if markErr := app.MarkAsErrorContext(ctx, urlCollection.Url, processorConfig.OriginCollection, err.Error()); markErr != nil {
 						app.Logger.Error("markErr: ", markErr.Error())
 						return
 					}
@@ -155,11 +163,12 @@ func (app *Crawler) crawlWorker(ctx context.Context, processorConfig ProcessorCo
 				App:           app,
 				Document:      doc,
 				UrlCollection: urlCollection,
-				Page:          page,
+				Page:          pwPage,
+				RodPage:       rodPage,
 				ApiResponse:   apiResponse,
 			}
 
-			if *app.engine.StoreHtml {
+			if *app.currentEngine().StoreHtml {
 				if StoreHtmlErr := app.SaveHtml(doc, urlCollection.Url); StoreHtmlErr != nil {
 					app.Logger.Error(StoreHtmlErr.Error())
 				}
@@ -180,9 +189,9 @@ func (app *Crawler) crawlWorker(ctx context.Context, processorConfig ProcessorCo
 						continue
 					}
 				}
-				app.insert(processorConfig.Entity, collections, urlCollection.Url)
+				app.insert(ctx, processorConfig.Entity, inheritSessionKey(collections, urlCollection), urlCollection.Url)
 				if !processorConfig.Preference.DoNotMarkAsComplete {
-					err := app.markAsComplete(urlCollection.Url, processorConfig.OriginCollection)
+					err := app.markAsComplete(ctx, urlCollection.Url, processorConfig.OriginCollection)
 					if err != nil {
 						app.Logger.Error(err.Error())
 						continue
@@ -200,7 +209,7 @@ func (app *Crawler) crawlWorker(ctx context.Context, processorConfig ProcessorCo
 					}
 					if currentPageUrl != "" && currentPageUrl != urlCollection.Url {
 						shouldMarkAsComplete = false
-						currentPageErr := app.SyncCurrentPageUrl(urlCollection.Url, currentPageUrl, processorConfig.OriginCollection)
+						currentPageErr := app.SyncCurrentPageUrlContext(ctx, urlCollection.Url, currentPageUrl, processorConfig.OriginCollection)
 						if currentPageErr != nil {
 							app.Logger.Fatal(currentPageErr.Error())
 							return
@@ -209,10 +218,10 @@ func (app *Crawler) crawlWorker(ctx context.Context, processorConfig ProcessorCo
 						shouldMarkAsComplete = true
 						atomic.AddInt32(counter, 1)
 					}
-					app.insert(processorConfig.Entity, collections, urlCollection.Url)
+					app.insert(ctx, processorConfig.Entity, inheritSessionKey(collections, urlCollection), urlCollection.Url)
 				})
 				if handleErr != nil {
-					markAsError := app.MarkAsError(urlCollection.Url, processorConfig.OriginCollection, handleErr.Error())
+					markAsError := app.MarkAsErrorContext(ctx, urlCollection.Url, processorConfig.OriginCollection, handleErr.Error())
 					if markAsError != nil {
 						app.Logger.Info(markAsError.Error())
 						return
@@ -220,7 +229,7 @@ func (app *Crawler) crawlWorker(ctx context.Context, processorConfig ProcessorCo
 					app.Logger.Error(handleErr.Error())
 				} else {
 					if !processorConfig.Preference.DoNotMarkAsComplete && shouldMarkAsComplete {
-						err := app.markAsComplete(urlCollection.Url, processorConfig.OriginCollection)
+						err := app.markAsComplete(ctx, urlCollection.Url, processorConfig.OriginCollection)
 						if err != nil {
 							app.Logger.Error(err.Error())
 							continue
@@ -238,10 +247,10 @@ func (app *Crawler) crawlWorker(ctx context.Context, processorConfig ProcessorCo
 						continue
 					}
 				}
-				app.insert(processorConfig.Entity, collections, urlCollection.Url)
+				app.insert(ctx, processorConfig.Entity, inheritSessionKey(collections, urlCollection), urlCollection.Url)
 
 				if !processorConfig.Preference.DoNotMarkAsComplete {
-					err := app.markAsComplete(urlCollection.Url, processorConfig.OriginCollection)
+					err := app.markAsComplete(ctx, urlCollection.Url, processorConfig.OriginCollection)
 					if err != nil {
 						app.Logger.Error(err.Error())
 						continue
@@ -254,7 +263,7 @@ func (app *Crawler) crawlWorker(ctx context.Context, processorConfig ProcessorCo
 				handleErr := v(crawlerCtx, func(collections []ProductDetailSelector, currentPageUrl string) {
 					if currentPageUrl != "" && currentPageUrl != urlCollection.Url {
 						shouldMarkAsComplete = false
-						currentPageErr := app.SyncCurrentPageUrl(urlCollection.Url, currentPageUrl, processorConfig.OriginCollection)
+						currentPageErr := app.SyncCurrentPageUrlContext(ctx, urlCollection.Url, currentPageUrl, processorConfig.OriginCollection)
 						if currentPageErr != nil {
 							app.Logger.Fatal(currentPageErr.Error())
 							return
@@ -269,10 +278,11 @@ func (app *Crawler) crawlWorker(ctx context.Context, processorConfig ProcessorCo
 						result := CrawlResult{
 							Results:       res,
 							UrlCollection: urlCollection,
-							Page:          page,
+							Page:          pwPage,
+							RodPage:       rodPage,
 							Document:      doc,
 						}
-						err := app.handleProductDetail(res, processorConfig, result)
+						err := app.handleProductDetail(ctx, res, processorConfig, result)
 						if err != nil {
 							app.Logger.Error(err.Error())
 							continue
@@ -280,7 +290,7 @@ func (app *Crawler) crawlWorker(ctx context.Context, processorConfig ProcessorCo
 					}
 				})
 				if handleErr != nil {
-					markAsError := app.MarkAsError(urlCollection.Url, processorConfig.OriginCollection, handleErr.Error())
+					markAsError := app.MarkAsErrorContext(ctx, urlCollection.Url, processorConfig.OriginCollection, handleErr.Error())
 					if markAsError != nil {
 						app.Logger.Info(markAsError.Error())
 						return
@@ -288,7 +298,7 @@ func (app *Crawler) crawlWorker(ctx context.Context, processorConfig ProcessorCo
 					app.Logger.Error(handleErr.Error())
 				} else {
 					if !processorConfig.Preference.DoNotMarkAsComplete && shouldMarkAsComplete {
-						err := app.markAsComplete(urlCollection.Url, processorConfig.OriginCollection)
+						err := app.markAsComplete(ctx, urlCollection.Url, processorConfig.OriginCollection)
 						if err != nil {
 							app.Logger.Error(err.Error())
 							continue
@@ -307,13 +317,14 @@ func (app *Crawler) crawlWorker(ctx context.Context, processorConfig ProcessorCo
 			crawlResult := CrawlResult{
 				Results:       results,
 				UrlCollection: urlCollection,
-				Page:          page,
+				Page:          pwPage,
+				RodPage:       rodPage,
 				Document:      doc,
 			}
 
 			select {
 			case resultChan <- crawlResult:
-				if isLocalEnv && atomic.LoadInt32(counter) >= int32(app.engine.DevCrawlLimit) {
+				if isLocalEnv && atomic.LoadInt32(counter) >= int32(app.currentEngine().DevCrawlLimit) {
 					//app.Logger.Warn("Dev Crawl limit %d reached!...", atomic.LoadInt32(counter))
 					return
 				}
@@ -321,21 +332,16 @@ func (app *Crawler) crawlWorker(ctx context.Context, processorConfig ProcessorCo
 			default:
 				app.Logger.Info("Channel is full, dropping Item")
 			}
-			if isLocalEnv && atomic.LoadInt32(counter) >= int32(app.engine.DevCrawlLimit) {
+			if isLocalEnv && atomic.LoadInt32(counter) >= int32(app.currentEngine().DevCrawlLimit) {
 				//app.Logger.Warn("Dev Crawl limit %d reached!", atomic.LoadInt32(counter))
 				return
 			}
 			// Signal that this goroutine is done
-			if atomic.AddInt32(&activeGoroutines, -1) == 0 {
+			if atomic.AddInt32(&app.activeWorkers, -1) == 0 {
 				// Rotate proxy only after all goroutines have finished processing
 				//currentProxy = rotateProxy()
 				//app.Logger.Info("Rotate proxy only after all goroutines have finished processing")
 			}
-			operationCount++                               // Increment the operation count
-			if operationCount%app.engine.SleepAfter == 0 { // Apply sleep after a certain number of operations
-				app.Logger.Info("Sleeping %d seconds after %d operations", app.engine.SleepDuration, app.engine.SleepAfter)
-				time.Sleep(time.Duration(app.engine.SleepDuration) * time.Second)
-			}
 		}
 	}
 }
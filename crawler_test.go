@@ -0,0 +1,150 @@
+package ninjacrawler
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// testSite is an https site crawled through its own tunneling proxy, recording the hosts requested through it.
+type testSite struct {
+	server *httptest.Server
+	proxy  *httptest.Server
+
+	mu    sync.Mutex
+	hosts map[string]int
+}
+
+func newTestSite(t *testing.T) *testSite {
+	t.Helper()
+	site := &testSite{hosts: map[string]int{}}
+	site.server = httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, "<html><head><title>%s</title></head><body></body></html>", r.URL.Path)
+	}))
+	site.proxy = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		site.mu.Lock()
+		site.hosts[r.Host]++
+		site.mu.Unlock()
+		tunnel(w, r)
+	}))
+	t.Cleanup(site.server.Close)
+	t.Cleanup(site.proxy.Close)
+	return site
+}
+
+// tunnel answers the CONNECT request r, piping the connection to the requested host.
+func tunnel(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodConnect {
+		http.Error(w, "only CONNECT is supported", http.StatusMethodNotAllowed)
+		return
+	}
+	upstream, err := net.Dial("tcp", r.Host)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadGateway)
+		return
+	}
+	conn, _, err := w.(http.Hijacker).Hijack()
+	if err != nil {
+		upstream.Close()
+		return
+	}
+	_, _ = conn.Write([]byte("HTTP/1.1 200 Connection established\r\n\r\n"))
+	go func() {
+		_, _ = io.Copy(upstream, conn)
+		upstream.Close()
+	}()
+	_, _ = io.Copy(conn, upstream)
+	conn.Close()
+}
+
+func (s *testSite) configs() []ProcessorConfig {
+	links := func(path string) func(CrawlerContext) []UrlCollection {
+		return func(ctx CrawlerContext) []UrlCollection {
+			prefix := strings.TrimSuffix(ctx.UrlCollection.Url, "/")
+			parent := ctx.UrlCollection.Url
+			return []UrlCollection{{Url: prefix + path + "1", Parent: parent}, {Url: prefix + path + "2", Parent: parent}}
+		}
+	}
+	return []ProcessorConfig{
+		{Entity: "categories", OriginCollection: baseCollection, Processor: links("/c")},
+		{Entity: "products", OriginCollection: "categories", Processor: links("/p")},
+	}
+}
+
+func TestCrawlTwoSitesConcurrently(t *testing.T) {
+	engines := []Engine{
+		{ConcurrentLimit: 2, InsecureSkipVerify: Bool(true)},
+		{ConcurrentLimit: 3, ProxyStrategy: ProxyStrategyRotationPerBatch, StickyProxy: Bool(true), InsecureSkipVerify: Bool(true)},
+	}
+	// The proxy manager, told about the proxies which failed
+	manager := httptest.NewServer(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {}))
+	defer manager.Close()
+	// A proxy dying after its first tunnel, failing the concurrent requests of the second site bound to it mid-crawl
+	var tunnels atomic.Int32
+	dying := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if tunnels.Add(1) > 1 {
+			w.WriteHeader(http.StatusProxyAuthRequired)
+			return
+		}
+		tunnel(w, r)
+	}))
+	defer dying.Close()
+
+	sites := make([]*testSite, len(engines))
+	apps := make([]*Crawler, len(engines))
+	for i, engine := range engines {
+		sites[i] = newTestSite(t)
+		apps[i] = newTestCrawler(t, fmt.Sprintf("site%d", i), sites[i].server.URL, engine)
+		apps[i].Config.Add("PROXY_SERVERS", sites[i].proxy.URL)
+		apps[i].Config.Add("SERVER_IP", manager.URL)
+	}
+	apps[1].Config.Add("PROXY_SERVERS", dying.URL+","+sites[1].proxy.URL)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	var wg sync.WaitGroup
+	for i := range apps {
+		wg.Add(1)
+		go func(app *Crawler, site *testSite) {
+			defer wg.Done()
+			app.Start()
+			app.CrawlContext(ctx, site.configs())
+		}(apps[i], sites[i])
+	}
+	wg.Wait()
+
+	for i, app := range apps {
+		site := sites[i]
+		products, err := app.store.FindUrlCollections(ctx, "products", UrlFilter{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(products) != 4 {
+			t.Errorf("site%d crawled %d products, want 4", i, len(products))
+		}
+		for _, product := range products {
+			if !strings.HasPrefix(product.Url, site.server.URL) {
+				t.Errorf("site%d stored product %s of another site", i, product.Url)
+			}
+		}
+		pending, err := app.store.CountUrlCollections(ctx, "categories", UrlFilter{Status: Bool(false)})
+		if err != nil || pending != 0 {
+			t.Errorf("site%d has %d pending categories, %v", i, pending, err)
+		}
+
+		site.mu.Lock()
+		host := strings.TrimPrefix(site.server.URL, "https://")
+		if len(site.hosts) != 1 || site.hosts[host] == 0 {
+			t.Errorf("proxy of site%d requested %v, want only %s", i, site.hosts, host)
+		}
+		site.mu.Unlock()
+	}
+}
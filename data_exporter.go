@@ -105,6 +105,9 @@ func convertFieldsToStrings(product ProductDetail) ([]string, error) {
 	value := reflect.ValueOf(product)
 
 	for i := 0; i < value.NumField(); i++ {
+		if value.Type().Field(i).Tag.Get("csv") == "-" {
+			continue
+		}
 		field := value.Field(i)
 		fieldName := strings.ToLower(value.Type().Field(i).Name)
 		convertedString := ""
@@ -3,14 +3,40 @@ package ninjacrawler
 import (
 	"cloud.google.com/go/datastore"
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	pb "google.golang.org/genproto/googleapis/datastore/v1"
+	"math"
 	"time"
 )
 
+const (
+	datastoreBatchSize     = 500  // Datastore limit for multi operations
+	datastoreMaxIndexBytes = 1500 // Longer strings can not be indexed
+)
+
+// datastoreJsonFields are stored as JSON strings because Datastore has no map type.
+var datastoreJsonFields = []string{"meta_data", "data"}
+
+// datastoreStore is the Cloud Datastore implementation of Store.
+// Every site gets its own namespace and every collection is a kind.
+// Set DATASTORE_EMULATOR_HOST to run it against the Datastore emulator.
+type datastoreStore struct {
+	app    *Crawler
+	client *datastore.Client
+}
+
+func newDatastoreStore(app *Crawler) *datastoreStore {
+	return &datastoreStore{
+		app:    app,
+		client: app.getDataStoreClient(),
+	}
+}
+
 func (app *Crawler) getDataStoreClient() *datastore.Client {
 	ctx := context.Background()
 
-	// Replace MongoDB connection logic with Datastore connection
 	client, err := datastore.NewClient(ctx, app.Config.EnvString("PROJECT_ID"))
 	if err != nil {
 		app.Logger.Error("Failed to create Datastore client: %v", err)
@@ -19,73 +45,454 @@ func (app *Crawler) getDataStoreClient() *datastore.Client {
 
 	return client
 }
-func (app *Crawler) InsertUrlCollectionsDS(model string, urlCollections []UrlCollection, parent string) {
-	ctx := context.Background()
-	client := app.getDataStoreClient()
-	defer client.Close()
 
-	// Datastore requires you to define keys for each entity
-	var keys []*datastore.Key
-	var entities []*UrlCollection
+// datastoreEntity is a schemaless entity which converts Map values into Datastore properties.
+type datastoreEntity Map
+
+func (e *datastoreEntity) Load(properties []datastore.Property) error {
+	*e = datastoreEntity{}
+	for _, p := range properties {
+		value := p.Value
+		if s, ok := value.(string); ok && contains(datastoreJsonFields, p.Name) {
+			var decoded interface{}
+			if err := json.Unmarshal([]byte(s), &decoded); err != nil {
+				return err
+			}
+			value = decoded
+		}
+		(*e)[p.Name] = value
+	}
+	return nil
+}
+
+func (e *datastoreEntity) Save() ([]datastore.Property, error) {
+	var properties []datastore.Property
+	for name, value := range *e {
+		property := datastore.Property{Name: name}
+		switch v := value.(type) {
+		case nil:
+		case string:
+			property.Value = v
+			property.NoIndex = len(v) > datastoreMaxIndexBytes || name == "error_log"
+		case bool:
+			property.Value = v
+		case int:
+			property.Value = int64(v)
+		case int32:
+			property.Value = int64(v)
+		case int64:
+			property.Value = v
+		case float64:
+			if v == math.Trunc(v) {
+				property.Value = int64(v)
+			} else {
+				property.Value = v
+			}
+		case time.Time:
+			property.Value = v
+		case *time.Time:
+			if v != nil {
+				property.Value = *v
+			}
+		default:
+			data, err := json.Marshal(v)
+			if err != nil {
+				return nil, fmt.Errorf("could not encode %s: %w", name, err)
+			}
+			property.Value = string(data)
+			property.NoIndex = true
+		}
+		properties = append(properties, property)
+	}
+	return properties, nil
+}
+
+// toUrlCollection decodes an entity through its json tags.
+func (e datastoreEntity) toUrlCollection() (UrlCollection, error) {
+	var urlCollection UrlCollection
+	data, err := json.Marshal(e)
+	if err != nil {
+		return urlCollection, err
+	}
+	err = json.Unmarshal(data, &urlCollection)
+	return urlCollection, err
+}
+
+// toSiteCollection decodes an entity through its json tags.
+func (e datastoreEntity) toSiteCollection() (SiteCollection, error) {
+	var site SiteCollection
+	data, err := json.Marshal(e)
+	if err != nil {
+		return site, err
+	}
+	err = json.Unmarshal(data, &site)
+	return site, err
+}
+
+// toProductDetail decodes the JSON blob of a product detail entity.
+func (e datastoreEntity) toProductDetail() (*ProductDetail, error) {
+	data, err := json.Marshal(e["data"])
+	if err != nil {
+		return nil, err
+	}
+	var productDetail ProductDetail
+	err = json.Unmarshal(data, &productDetail)
+	return &productDetail, err
+}
+
+// newDatastoreEntity converts a document into an entity, parsing timestamps back into time.Time.
+func newDatastoreEntity(document interface{}) (datastoreEntity, error) {
+	doc, err := toMap(document)
+	if err != nil {
+		return nil, err
+	}
+	for _, field := range []string{"created_at", "updated_at", "started_at", "ended_at"} {
+		if s, ok := doc[field].(string); ok {
+			if t, err := time.Parse(time.RFC3339Nano, s); err == nil {
+				doc[field] = t
+			}
+		}
+	}
+	return datastoreEntity(doc), nil
+}
+
+func (s *datastoreStore) key(collection, name string) *datastore.Key {
+	var key *datastore.Key
+	if name == "" {
+		key = datastore.IncompleteKey(collection, nil)
+	} else {
+		key = datastore.NameKey(collection, name, nil)
+	}
+	key.Namespace = s.app.Name
+	return key
+}
+
+// documentKey mirrors the MongoDB unique index: url plus the index fields of the collection,
+// or an auto generated id for collections listed in ExcludeUniqueUrlEntities.
+func (s *datastoreStore) documentKey(collection string, index []string, document datastoreEntity) *datastore.Key {
+	if contains(s.app.preference.ExcludeUniqueUrlEntities, collection) {
+		return s.key(collection, "")
+	}
+	url, _ := document["url"].(string)
+	name := url
+	for _, field := range index {
+		name += keySeparator + fmt.Sprint(Map(document).Get(field))
+	}
+	return s.key(collection, name)
+}
+
+func (s *datastoreStore) query(collection string) *datastore.Query {
+	return datastore.NewQuery(collection).Namespace(s.app.Name)
+}
+
+func (s *datastoreStore) filterQuery(collection string, filter UrlFilter) *datastore.Query {
+	q := s.query(collection)
+	if filter.Status != nil {
+		q = q.FilterField("status", "=", *filter.Status)
+	}
+	if filter.Error != nil {
+		q = q.FilterField("error", "=", *filter.Error)
+	}
+	if filter.MinAttempts != nil {
+		q = q.FilterField("attempts", ">=", int64(*filter.MinAttempts))
+	}
+	if filter.MaxAttempts != nil {
+		q = q.FilterField("attempts", "<", int64(*filter.MaxAttempts))
+	}
+	if filter.Limit > 0 {
+		q = q.Limit(filter.Limit)
+	}
+	return q
+}
+
+// insert writes documents in batches. Unless replace is set, documents whose key already exists are skipped.
+func (s *datastoreStore) insert(ctx context.Context, collection string, documents []interface{}, replace bool) error {
+	ctx, cancel := context.WithTimeout(ctx, dbTimeout)
+	defer cancel()
 
+	index := s.app.collectionIndex(collection)
+	for start := 0; start < len(documents); start += datastoreBatchSize {
+		end := min(start+datastoreBatchSize, len(documents))
+		var keys []*datastore.Key
+		var entities []datastoreEntity
+		for _, document := range documents[start:end] {
+			entity, err := newDatastoreEntity(document)
+			if err != nil {
+				return err
+			}
+			keys = append(keys, s.documentKey(collection, index, entity))
+			entities = append(entities, entity)
+		}
+
+		if !replace {
+			var err error
+			keys, entities, err = s.withoutExisting(ctx, keys, entities)
+			if err != nil {
+				return err
+			}
+		}
+		if len(keys) == 0 {
+			continue
+		}
+		if _, err := s.client.PutMulti(ctx, keys, entities); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// withoutExisting drops the entities whose complete key is already stored.
+// A failed lookup is returned, as writing the entities anyway would reset the documents already crawled.
+func (s *datastoreStore) withoutExisting(ctx context.Context, keys []*datastore.Key, entities []datastoreEntity) ([]*datastore.Key, []datastoreEntity, error) {
+	var completeKeys []*datastore.Key
+	for _, key := range keys {
+		if !key.Incomplete() {
+			completeKeys = append(completeKeys, key)
+		}
+	}
+	if len(completeKeys) == 0 {
+		return keys, entities, nil
+	}
+
+	existing := make(map[string]bool)
+	dst := make([]datastoreEntity, len(completeKeys))
+	err := s.client.GetMulti(ctx, completeKeys, dst)
+	var multiErr datastore.MultiError
+	if errors.As(err, &multiErr) {
+		for i, e := range multiErr {
+			if e == nil {
+				existing[completeKeys[i].String()] = true
+			} else if !errors.Is(e, datastore.ErrNoSuchEntity) {
+				return nil, nil, e
+			}
+		}
+	} else if err != nil {
+		return nil, nil, err
+	} else {
+		for _, key := range completeKeys {
+			existing[key.String()] = true
+		}
+	}
+
+	var newKeys []*datastore.Key
+	var newEntities []datastoreEntity
+	seen := make(map[string]bool)
+	for i, key := range keys {
+		if !key.Incomplete() {
+			if existing[key.String()] || seen[key.String()] {
+				continue
+			}
+			seen[key.String()] = true
+		}
+		newKeys = append(newKeys, key)
+		newEntities = append(newEntities, entities[i])
+	}
+	return newKeys, newEntities, nil
+}
+
+func (s *datastoreStore) InsertUrlCollections(ctx context.Context, collection string, urlCollections []UrlCollection) error {
+	documents := make([]interface{}, 0, len(urlCollections))
 	for _, urlCollection := range urlCollections {
-		// Create a new Datastore key using the model and the URL as a unique identifier
-		key := datastore.NameKey(model, urlCollection.Url, nil)
-		keys = append(keys, key)
-		// Update the URL collection with the appropriate fields
-		urlCollection.Parent = parent
-		urlCollection.Status = false
-		urlCollection.Error = false
-		urlCollection.Attempts = 0
-		urlCollection.CreatedAt = time.Now()
-		entities = append(entities, &urlCollection)
-	}
-
-	// Use PutMulti to insert multiple entities
-	_, err := client.PutMulti(ctx, keys, entities)
+		documents = append(documents, urlCollection)
+	}
+	return s.insert(ctx, collection, documents, false)
+}
+
+func (s *datastoreStore) FindUrlCollections(ctx context.Context, collection string, filter UrlFilter) ([]UrlCollection, error) {
+	ctx, cancel := context.WithTimeout(ctx, dbTimeout)
+	defer cancel()
+
+	var entities []datastoreEntity
+	if _, err := s.client.GetAll(ctx, s.filterQuery(collection, filter), &entities); err != nil {
+		return nil, err
+	}
+	results := make([]UrlCollection, 0, len(entities))
+	for _, entity := range entities {
+		urlCollection, err := entity.toUrlCollection()
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, urlCollection)
+	}
+	return results, nil
+}
+
+// findKey returns the key of the first entity stored for url.
+func (s *datastoreStore) findKey(ctx context.Context, collection, url string) (*datastore.Key, error) {
+	keys, err := s.client.GetAll(ctx, s.query(collection).FilterField("url", "=", url).KeysOnly().Limit(1), nil)
 	if err != nil {
-		app.Logger.Error("Failed to insert URL collections: %v", err)
+		return nil, err
 	}
+	if len(keys) == 0 {
+		return nil, datastore.ErrNoSuchEntity
+	}
+	return keys[0], nil
 }
-func (app *Crawler) getUrlCollectionsDS(collection string) []UrlCollection {
-	ctx := context.Background()
-	client := app.getDataStoreClient()
-	defer client.Close()
 
-	var urlCollections []UrlCollection
-	query := datastore.NewQuery(collection).
-		Filter("Status =", false).
-		Filter("Attempts <", app.engine.MaxRetryAttempts).
-		Limit(dbFilterLimit)
+func (s *datastoreStore) FindUrlCollection(ctx context.Context, collection string, url string) (*UrlCollection, error) {
+	ctx, cancel := context.WithTimeout(ctx, dbTimeout)
+	defer cancel()
 
-	_, err := client.GetAll(ctx, query, &urlCollections)
+	key, err := s.findKey(ctx, collection, url)
+	if err != nil {
+		return nil, err
+	}
+	var entity datastoreEntity
+	if err := s.client.Get(ctx, key, &entity); err != nil {
+		return nil, err
+	}
+	urlCollection, err := entity.toUrlCollection()
 	if err != nil {
-		app.Logger.Error("Failed to retrieve URL collections: %v", err)
+		return nil, err
 	}
+	return &urlCollection, nil
+}
+
+func (s *datastoreStore) UpdateUrlCollection(ctx context.Context, collection string, url string, fields Map) error {
+	ctx, cancel := context.WithTimeout(ctx, dbTimeout)
+	defer cancel()
 
-	return urlCollections
+	key, err := s.findKey(ctx, collection, url)
+	if errors.Is(err, datastore.ErrNoSuchEntity) {
+		return nil // same as an UpdateOne without match
+	}
+	if err != nil {
+		return err
+	}
+	_, err = s.client.RunInTransaction(ctx, func(tx *datastore.Transaction) error {
+		var entity datastoreEntity
+		if err := tx.Get(key, &entity); err != nil {
+			return err
+		}
+		for name, value := range fields {
+			entity[name] = value
+		}
+		_, err := tx.Put(key, &entity)
+		return err
+	})
+	return err
 }
-func (app *Crawler) markAsCompleteDS(url string, dbCollection string) error {
-	ctx := context.Background()
-	client := app.getDataStoreClient()
-	defer client.Close()
 
-	// Get the entity by its key (URL)
-	key := datastore.NameKey(dbCollection, url, nil)
-	var urlCollection UrlCollection
-	if err := client.Get(ctx, key, &urlCollection); err != nil {
-		return fmt.Errorf("could not retrieve entity: %v", err)
+func (s *datastoreStore) CountUrlCollections(ctx context.Context, collection string, filter UrlFilter) (int, error) {
+	ctx, cancel := context.WithTimeout(ctx, dbTimeout)
+	defer cancel()
+
+	filter.Limit = 0
+	result, err := s.client.RunAggregationQuery(ctx, s.filterQuery(collection, filter).NewAggregationQuery().WithCount("count"))
+	if err != nil {
+		return 0, err
+	}
+	count, ok := result["count"].(*pb.Value)
+	if !ok {
+		return 0, fmt.Errorf("unexpected count result: %v", result["count"])
 	}
+	return int(count.GetIntegerValue()), nil
+}
 
-	timeNow := time.Now()
-	// Update the fields
-	urlCollection.Status = true
-	urlCollection.UpdatedAt = &timeNow
+// SaveProductDetail stores the product as a JSON blob, keyed by its url.
+func (s *datastoreStore) SaveProductDetail(ctx context.Context, collection string, productDetail *ProductDetail) error {
+	ctx, cancel := context.WithTimeout(ctx, dbTimeout)
+	defer cancel()
 
-	// Save the updated entity
-	if _, err := client.Put(ctx, key, &urlCollection); err != nil {
-		return fmt.Errorf("could not mark as complete: %v", err)
+	key := s.key(collection, productDetail.Url)
+	if contains(s.app.preference.ExcludeUniqueUrlEntities, collection) {
+		key = s.key(collection, "")
+	}
+	entity := datastoreEntity{
+		"url":        productDetail.Url,
+		"data":       productDetail,
+		"created_at": time.Now(),
 	}
+	_, err := s.client.Put(ctx, key, &entity)
+	return err
+}
 
+func (s *datastoreStore) FindProductDetail(ctx context.Context, collection string, url string) (*ProductDetail, error) {
+	ctx, cancel := context.WithTimeout(ctx, dbTimeout)
+	defer cancel()
+
+	key, err := s.findKey(ctx, collection, url)
+	if err != nil {
+		return nil, err
+	}
+	var entity datastoreEntity
+	if err := s.client.Get(ctx, key, &entity); err != nil {
+		return nil, err
+	}
+	return entity.toProductDetail()
+}
+
+func (s *datastoreStore) FindProductDetails(ctx context.Context, collection string, skip, limit int) ([]ProductDetail, error) {
+	ctx, cancel := context.WithTimeout(ctx, dbTimeout)
+	defer cancel()
+
+	var entities []datastoreEntity
+	q := s.query(collection).Order("__key__").Offset(skip).Limit(limit)
+	if _, err := s.client.GetAll(ctx, q, &entities); err != nil {
+		return nil, err
+	}
+	results := make([]ProductDetail, 0, len(entities))
+	for _, entity := range entities {
+		productDetail, err := entity.toProductDetail()
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, *productDetail)
+	}
+	return results, nil
+}
+
+func (s *datastoreStore) InsertSite(ctx context.Context, site SiteCollection) error {
+	return s.insert(ctx, baseCollection, []interface{}{site}, false)
+}
+
+func (s *datastoreStore) FindSite(ctx context.Context, url string) (*SiteCollection, error) {
+	ctx, cancel := context.WithTimeout(ctx, dbTimeout)
+	defer cancel()
+
+	key, err := s.findKey(ctx, baseCollection, url)
+	if err != nil {
+		return nil, err
+	}
+	var entity datastoreEntity
+	if err := s.client.Get(ctx, key, &entity); err != nil {
+		return nil, err
+	}
+	site, err := entity.toSiteCollection()
+	if err != nil {
+		return nil, err
+	}
+	return &site, nil
+}
+
+func (s *datastoreStore) UpdateSite(ctx context.Context, url string, fields Map) error {
+	return s.UpdateUrlCollection(ctx, baseCollection, url, fields)
+}
+
+// Drop deletes every entity in the namespace of the site.
+func (s *datastoreStore) Drop(ctx context.Context) error {
+	ctx, cancel := context.WithTimeout(ctx, dbTimeout)
+	defer cancel()
+
+	kinds, err := s.client.GetAll(ctx, datastore.NewQuery("__kind__").Namespace(s.app.Name).KeysOnly(), nil)
+	if err != nil {
+		return err
+	}
+	for _, kind := range kinds {
+		keys, err := s.client.GetAll(ctx, s.query(kind.Name).KeysOnly(), nil)
+		if err != nil {
+			return err
+		}
+		for start := 0; start < len(keys); start += datastoreBatchSize {
+			end := min(start+datastoreBatchSize, len(keys))
+			if err := s.client.DeleteMulti(ctx, keys[start:end]); err != nil {
+				return err
+			}
+		}
+	}
 	return nil
 }
+
+func (s *datastoreStore) Close() error {
+	return s.client.Close()
+}
@@ -0,0 +1,165 @@
+package ninjacrawler
+
+import (
+	"context"
+	pb "google.golang.org/genproto/googleapis/datastore/v1"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"net"
+	"os"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// failingDatastore is a Datastore server whose lookups fail, counting the commits it receives.
+type failingDatastore struct {
+	pb.UnimplementedDatastoreServer
+	commits atomic.Int32
+}
+
+func (d *failingDatastore) Lookup(context.Context, *pb.LookupRequest) (*pb.LookupResponse, error) {
+	return nil, status.Error(codes.PermissionDenied, "lookup denied")
+}
+
+func (d *failingDatastore) Commit(context.Context, *pb.CommitRequest) (*pb.CommitResponse, error) {
+	d.commits.Add(1)
+	return &pb.CommitResponse{}, nil
+}
+
+func TestDatastoreInsertKeepsDocumentsWhenLookupFails(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := grpc.NewServer()
+	fake := &failingDatastore{}
+	pb.RegisterDatastoreServer(server, fake)
+	go server.Serve(listener)
+	defer server.Stop()
+
+	app := newTestCrawler(t, "datastore_lookup", "http://example.test")
+	t.Setenv("DATASTORE_EMULATOR_HOST", listener.Addr().String())
+	t.Setenv("PROJECT_ID", "ninjacrawler-test")
+	store := newDatastoreStore(app)
+	defer store.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	err = store.InsertUrlCollections(ctx, "products", []UrlCollection{{Url: "http://example.test/1"}})
+	if err == nil {
+		t.Fatal("insert succeeded although the lookup of the existing documents failed")
+	}
+	if commits := fake.commits.Load(); commits != 0 {
+		t.Fatalf("insert wrote %d batches without knowing the existing documents", commits)
+	}
+}
+
+func TestDatastoreDocumentKeyUsesIndexOfCollection(t *testing.T) {
+	app := newTestCrawler(t, "datastore_key", "http://example.test")
+	app.registerCollectionIndexes([]ProcessorConfig{
+		{Entity: "products", OriginCollection: "categories", CollectionIndex: &[]string{"url", "meta_data.sku"}},
+	})
+	app.processor.Store(&ProcessorConfig{Entity: "categories", CollectionIndex: &[]string{"meta_data.page"}})
+	store := &datastoreStore{app: app}
+
+	entity, err := newDatastoreEntity(UrlCollection{Url: "http://example.test/1", MetaData: Map{"sku": "A1", "page": 2}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := store.documentKey("products", app.collectionIndex("products"), entity).Name, "http://example.test/1"+keySeparator+"A1"; got != want {
+		t.Errorf("products key = %q, want %q", got, want)
+	}
+	if got, want := store.documentKey("categories", app.collectionIndex("categories"), entity).Name, "http://example.test/1"; got != want {
+		t.Errorf("categories key = %q, want %q", got, want)
+	}
+}
+
+// TestDatastoreEmulator runs the Store contract against the Datastore emulator, e.g.
+// gcloud beta emulators datastore start --no-store-on-disk and $(gcloud beta emulators datastore env-init).
+func TestDatastoreEmulator(t *testing.T) {
+	if os.Getenv("DATASTORE_EMULATOR_HOST") == "" {
+		t.Skip("DATASTORE_EMULATOR_HOST is not set")
+	}
+	if os.Getenv("PROJECT_ID") == "" {
+		t.Setenv("PROJECT_ID", "ninjacrawler-test")
+	}
+	app := newTestCrawler(t, "datastore_emulator", "http://example.test")
+	app.registerCollectionIndexes([]ProcessorConfig{{Entity: "products", CollectionIndex: &[]string{"meta_data.sku"}}})
+	store := newDatastoreStore(app)
+	defer store.Close()
+	ctx := context.Background()
+	if err := store.Drop(ctx); err != nil {
+		t.Fatal(err)
+	}
+	defer store.Drop(ctx)
+
+	urls := []UrlCollection{
+		{Url: "http://example.test/1", MetaData: Map{"sku": "A1"}},
+		{Url: "http://example.test/1", MetaData: Map{"sku": "A2"}},
+		{Url: "http://example.test/2", MetaData: Map{"sku": "B1"}},
+	}
+	if err := store.InsertUrlCollections(ctx, "products", urls); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.UpdateUrlCollection(ctx, "products", "http://example.test/2", Map{"status": true, "attempts": 1}); err != nil {
+		t.Fatal(err)
+	}
+	// Inserting again must neither duplicate nor reset the crawled document
+	if err := store.InsertUrlCollections(ctx, "products", urls); err != nil {
+		t.Fatal(err)
+	}
+
+	eventually(t, func() bool {
+		count, err := store.CountUrlCollections(ctx, "products", UrlFilter{})
+		return err == nil && count == 3
+	})
+	pending, err := store.CountUrlCollections(ctx, "products", UrlFilter{Status: Bool(false)})
+	if err != nil || pending != 2 {
+		t.Fatalf("pending = %d, %v, want 2", pending, err)
+	}
+	crawled, err := store.FindUrlCollection(ctx, "products", "http://example.test/2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !crawled.Status || crawled.Attempts != 1 {
+		t.Errorf("crawled document was reset: %+v", crawled)
+	}
+
+	product := &ProductDetail{Url: "http://example.test/2", PageTitle: "Product"}
+	if err := store.SaveProductDetail(ctx, "product_details", product); err != nil {
+		t.Fatal(err)
+	}
+	var found *ProductDetail
+	eventually(t, func() bool {
+		found, err = store.FindProductDetail(ctx, "product_details", product.Url)
+		return err == nil
+	})
+	if found.PageTitle != product.PageTitle {
+		t.Errorf("product title = %q, want %q", found.PageTitle, product.PageTitle)
+	}
+
+	if err := store.InsertSite(ctx, SiteCollection{Url: "http://example.test", BaseUrl: "http://example.test"}); err != nil {
+		t.Fatal(err)
+	}
+	eventually(t, func() bool {
+		_, err := store.FindSite(ctx, "http://example.test")
+		return err == nil
+	})
+	if err := store.UpdateSite(ctx, "http://example.test", Map{"status": true}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// eventually polls condition, as queries of Datastore are eventually consistent.
+func eventually(t *testing.T, condition func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(10 * time.Second)
+	for !condition() {
+		if time.Now().After(deadline) {
+			t.Fatal("condition not met within 10s")
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+}
@@ -1,9 +1,11 @@
 package ninjacrawler
 
 import (
+	"context"
 	"fmt"
 	"github.com/playwright-community/playwright-go"
 	"net/url"
+	"reflect"
 	"strings"
 	"time"
 )
@@ -11,7 +13,7 @@ import (
 type Engine struct {
 	Adapter                *string
 	ForceInstallPlaywright bool
-	Provider               string // http,playwright,zenrows
+	Provider               string // http, zenrows or a provider registered with RegisterFetcher
 	ProviderOption         ProviderQueryOption
 	BrowserType            string
 	ConcurrentLimit        int
@@ -37,12 +39,19 @@ type Engine struct {
 	*/
 	Timeout                 time.Duration
 	WaitForDynamicRendering bool
-	SleepAfter              int
+	/*
+		Deprecated: SleepAfter is replaced by RateLimit and not work anymore
+	*/
+	SleepAfter int
+	/*
+		RateLimit is enforced per target host across all workers and fetchers, unlimited when nil
+	*/
+	RateLimit               *RateLimit
 	MaxRetryAttempts        int
 	IgnoreRetryOnValidation *bool
 	Args                    []string
 	/*
-		SleepDuration in seconds
+		Deprecated: SleepDuration is replaced by RateLimit and not work anymore
 	*/
 	SleepDuration int
 	/*
@@ -59,7 +68,54 @@ type Engine struct {
 	SimulateMouse             *bool
 	OpenDevTools              *bool
 	TrackRedirection          *bool
-	ApplyRandomSleep          *bool
+	/*
+		Deprecated: ApplyRandomSleep is replaced by RateLimit and not work anymore
+	*/
+	ApplyRandomSleep *bool
+	/*
+		ConditionalRequests sends If-None-Match and If-Modified-Since from the previous crawl, unchanged pages are skipped
+	*/
+	ConditionalRequests *bool
+	/*
+		ResponseCache serves static responses from storage/cache, only in the local environment
+	*/
+	ResponseCache *bool
+	/*
+		StickyProxy binds a proxy to the session key of the url collection, by default its parent url, until the proxy fails
+	*/
+	StickyProxy *bool
+	/*
+		Escalation lists the tiers a blocked static fetch escalates through, e.g. http, proxy, premium_proxy, browser
+	*/
+	Escalation []string
+	/*
+		ChallengeMarkers are strings of the challenge page of the site, besides the ones of the common bot protections
+	*/
+	ChallengeMarkers []string
+	/*
+		MaxBrowsers caps the browsers kept alive by the browser pool of dynamic crawls
+	*/
+	MaxBrowsers int
+	/*
+		BrowserRecycleAfter is the number of pages after which a browser of the pool is replaced, never when 0
+	*/
+	BrowserRecycleAfter int
+	/*
+		InsecureSkipVerify skips the verification of the TLS certificates of static fetches, off by default
+	*/
+	InsecureSkipVerify *bool
+	/*
+		MaxConnsPerHost caps the connections of static fetches per host and proxy, unlimited when 0
+	*/
+	MaxConnsPerHost int
+	/*
+		MaxIdleConnsPerHost is the number of idle keep-alive connections kept per host and proxy
+	*/
+	MaxIdleConnsPerHost int
+	/*
+		Unset resets inherited options to their package default, e.g. Unset: []string{"WaitForSelector"}
+	*/
+	Unset []string
 }
 type ProviderQueryOption struct {
 	JsRender             bool
@@ -85,51 +141,114 @@ type ProviderQueryOption struct {
 }
 
 func (app *Crawler) SetBrowserType(browserType string) *Crawler {
-	app.engine.BrowserType = browserType
+	app.siteEngine.BrowserType = browserType
 	return app
 }
 
 func (app *Crawler) SetConcurrentLimit(concurrentLimit int) *Crawler {
-	app.engine.ConcurrentLimit = concurrentLimit
+	app.siteEngine.ConcurrentLimit = concurrentLimit
 	return app
 }
 
 func (app *Crawler) IsDynamicPage(isDynamic bool) *Crawler {
-	app.engine.IsDynamic = &isDynamic
+	app.siteEngine.IsDynamic = &isDynamic
 	app.toggleClient()
 	return app
 }
 
 func (app *Crawler) SetCrawlLimit(crawlLimit int) *Crawler {
-	app.engine.DevCrawlLimit = crawlLimit
+	app.siteEngine.DevCrawlLimit = crawlLimit
 	return app
 }
 func (app *Crawler) SetBlockResources(block bool) *Crawler {
-	app.engine.BlockResources = block
+	app.siteEngine.BlockResources = block
 	return app
 }
 
 func (app *Crawler) SetCookieConsent(action *CookieAction) *Crawler {
-	app.engine.CookieConsent = action
+	app.siteEngine.CookieConsent = action
 	return app
 }
 func (app *Crawler) SetTimeout(timeout time.Duration) *Crawler {
-	app.engine.Timeout = timeout * time.Second
+	app.siteEngine.Timeout = timeout * time.Second
 	return app
 }
 func (app *Crawler) DisableJavaScript() *Crawler {
-	app.engine.JavaScriptEnabled = false
+	app.siteEngine.JavaScriptEnabled = false
 	return app
 }
 func (app *Crawler) WaitForDynamicRendering() *Crawler {
-	app.engine.WaitForDynamicRendering = true
+	app.siteEngine.WaitForDynamicRendering = true
 	return app
 }
+
+/*
+Deprecated: SetSleepAfter is replaced by SetRateLimit and not work anymore
+*/
 func (app *Crawler) SetSleepAfter(sleepAfter int) *Crawler {
-	app.engine.SleepAfter = sleepAfter
+	app.siteEngine.SleepAfter = sleepAfter
+	return app
+}
+func (app *Crawler) SetRateLimit(requestsPerSecond float64, burst int) *Crawler {
+	app.siteEngine.RateLimit = &RateLimit{RequestsPerSecond: requestsPerSecond, Burst: burst}
 	return app
 }
 
+// resolveEngine returns the engine of a processor: the site engine with the overrides layered on top in order.
+// The site engine is copied, so the overrides of one processor never leak into the next one.
+func (app *Crawler) resolveEngine(overrides ...*Engine) *Engine {
+	return app.layerEngine(app.siteEngine, overrides...)
+}
+
+// layerEngine returns a copy of base with the overrides layered on top in order.
+// The options listed in Unset of an override are reset before its other options are applied.
+func (app *Crawler) layerEngine(base *Engine, overrides ...*Engine) *Engine {
+	engine := *base
+	engine.BlockedURLs = append([]string(nil), base.BlockedURLs...)
+	for _, override := range overrides {
+		if override == nil {
+			continue
+		}
+		app.unsetEngineOptions(&engine, override.Unset)
+		app.overrideEngineDefaults(&engine, override)
+	}
+	return &engine
+}
+
+// unsetEngineOptions resets the named options of engine to their package default.
+func (app *Crawler) unsetEngineOptions(engine *Engine, options []string) {
+	defaults := reflect.ValueOf(getDefaultEngine())
+	target := reflect.ValueOf(engine).Elem()
+	for _, option := range options {
+		field := target.FieldByName(option)
+		if !field.IsValid() || option == "Unset" {
+			app.Logger.Warn("Unknown engine option %q in Unset", option)
+			continue
+		}
+		field.Set(defaults.FieldByName(option))
+	}
+}
+
+// engineFor returns the engine of the navigation in ctx, the engine of the running processor when it has none.
+func (app *Crawler) engineFor(ctx context.Context) *Engine {
+	if engine := crawlRequestFrom(ctx).Engine; engine != nil {
+		return engine
+	}
+	return app.currentEngine()
+}
+
+// warnInsecureEngine warns when the static fetches of the processor of collection skip the verification of TLS certificates.
+func (app *Crawler) warnInsecureEngine(collection string, engine *Engine) {
+	if *engine.InsecureSkipVerify {
+		app.Logger.Warn("%s: TLS certificates are not verified, InsecureSkipVerify is on", collection)
+	}
+}
+
+// currentEngine returns the resolved engine of the running processor, the site engine before the first one starts.
+func (app *Crawler) currentEngine() *Engine {
+	return app.engine.Load()
+}
+
 // Todo: getProxyList should be generate dynamically in future
 func (app *Crawler) getProxyList() []Proxy {
 	proxyEnv := app.Config.GetString("PROXY_SERVERS")
@@ -145,66 +264,71 @@ func (app *Crawler) getProxyList() []Proxy {
 	return proxies
 }
 func (app *Crawler) BuildQueryString() string {
+	return buildQueryString(app.currentEngine().ProviderOption)
+}
+
+// buildQueryString returns the zenrows api parameters of option.
+func buildQueryString(option ProviderQueryOption) string {
 	params := url.Values{}
 
-	if app.engine.ProviderOption.JsRender {
+	if option.JsRender {
 		params.Add("js_render", "true")
 	}
-	if app.engine.ProviderOption.JsInstructions != "" {
-		params.Add("js_instructions", app.engine.ProviderOption.JsInstructions)
+	if option.JsInstructions != "" {
+		params.Add("js_instructions", option.JsInstructions)
 	}
-	if app.engine.ProviderOption.CustomHeaders {
+	if option.CustomHeaders {
 		params.Add("custom_headers", "true")
 	}
-	if app.engine.ProviderOption.PremiumProxy {
+	if option.PremiumProxy {
 		params.Add("premium_proxy", "true")
 	}
-	if app.engine.ProviderOption.ProxyCountry != "" {
-		params.Add("proxy_country", app.engine.ProviderOption.ProxyCountry)
+	if option.ProxyCountry != "" {
+		params.Add("proxy_country", option.ProxyCountry)
 	}
-	if app.engine.ProviderOption.SessionID != 0 {
-		params.Add("session_id", fmt.Sprintf("%d", app.engine.ProviderOption.SessionID))
+	if option.SessionID != 0 {
+		params.Add("session_id", fmt.Sprintf("%d", option.SessionID))
 	}
-	if app.engine.ProviderOption.Device != "" {
-		params.Add("device", app.engine.ProviderOption.Device)
+	if option.Device != "" {
+		params.Add("device", option.Device)
 	} else {
 		params.Add("device", "desktop")
 	}
-	if app.engine.ProviderOption.OriginalStatus {
+	if option.OriginalStatus {
 		params.Add("original_status", "true")
 	}
-	if app.engine.ProviderOption.AllowedStatusCodes != "" {
-		params.Add("allowed_status_codes", app.engine.ProviderOption.AllowedStatusCodes)
+	if option.AllowedStatusCodes != "" {
+		params.Add("allowed_status_codes", option.AllowedStatusCodes)
 	}
-	if app.engine.ProviderOption.WaitFor != "" {
-		params.Add("wait_for", app.engine.ProviderOption.WaitFor)
+	if option.WaitFor != "" {
+		params.Add("wait_for", option.WaitFor)
 	}
-	if app.engine.ProviderOption.Wait != 0 {
-		params.Add("wait", fmt.Sprintf("%d", app.engine.ProviderOption.Wait))
+	if option.Wait != 0 {
+		params.Add("wait", fmt.Sprintf("%d", option.Wait))
 	}
-	if app.engine.ProviderOption.BlockResources != "" {
-		params.Add("block_resources", app.engine.ProviderOption.BlockResources)
+	if option.BlockResources != "" {
+		params.Add("block_resources", option.BlockResources)
 	}
-	if app.engine.ProviderOption.JSONResponse {
+	if option.JSONResponse {
 		params.Add("json_response", "true")
 	}
-	if app.engine.ProviderOption.CSSExtractor != "" {
-		params.Add("css_extractor", app.engine.ProviderOption.CSSExtractor)
+	if option.CSSExtractor != "" {
+		params.Add("css_extractor", option.CSSExtractor)
 	}
-	if app.engine.ProviderOption.Autoparse {
+	if option.Autoparse {
 		params.Add("autoparse", "true")
 	}
-	if app.engine.ProviderOption.MarkdownResponse {
+	if option.MarkdownResponse {
 		params.Add("markdown_response", "true")
 	}
-	if app.engine.ProviderOption.Screenshot {
+	if option.Screenshot {
 		params.Add("screenshot", "true")
 	}
-	if app.engine.ProviderOption.ScreenshotFullPage {
+	if option.ScreenshotFullPage {
 		params.Add("screenshot_fullpage", "true")
 	}
-	if app.engine.ProviderOption.ScreenshotSelector != "" {
-		params.Add("screenshot_selector", app.engine.ProviderOption.ScreenshotSelector)
+	if option.ScreenshotSelector != "" {
+		params.Add("screenshot_selector", option.ScreenshotSelector)
 	}
 	return params.Encode()
 }
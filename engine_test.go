@@ -0,0 +1,100 @@
+package ninjacrawler
+
+import (
+	"testing"
+	"time"
+)
+
+func TestResolveEngine(t *testing.T) {
+	tests := []struct {
+		name            string
+		site            Engine
+		overrides       []*Engine
+		concurrentLimit int
+		timeout         time.Duration
+		waitForSelector *string
+	}{
+		{
+			name:            "site defaults",
+			site:            Engine{ConcurrentLimit: 3},
+			concurrentLimit: 3,
+			timeout:         30 * time.Second,
+		},
+		{
+			name:            "processor override",
+			site:            Engine{ConcurrentLimit: 3, WaitForSelector: String(".site")},
+			overrides:       []*Engine{{Timeout: 5}}, // Engine timeouts are given in seconds
+			concurrentLimit: 3,
+			timeout:         5 * time.Second,
+			waitForSelector: String(".site"),
+		},
+		{
+			name:            "later overrides win",
+			site:            Engine{ConcurrentLimit: 3},
+			overrides:       []*Engine{{ConcurrentLimit: 5, WaitForSelector: String(".processor")}, nil, {ConcurrentLimit: 1}},
+			concurrentLimit: 1,
+			timeout:         30 * time.Second,
+			waitForSelector: String(".processor"),
+		},
+		{
+			name:            "unset inherited option",
+			site:            Engine{WaitForSelector: String(".site")},
+			overrides:       []*Engine{{Unset: []string{"WaitForSelector", "ConcurrentLimit"}}},
+			concurrentLimit: getDefaultEngine().ConcurrentLimit,
+			timeout:         30 * time.Second,
+		},
+		{
+			name:            "unset before override",
+			site:            Engine{WaitForSelector: String(".site")},
+			overrides:       []*Engine{{Unset: []string{"WaitForSelector"}, WaitForSelector: String(".retry")}},
+			concurrentLimit: getDefaultEngine().ConcurrentLimit,
+			timeout:         30 * time.Second,
+			waitForSelector: String(".retry"),
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			app := newTestCrawler(t, "engine", "http://example.test", test.site)
+			site := *app.siteEngine
+			engine := app.resolveEngine(test.overrides...)
+
+			if engine.ConcurrentLimit != test.concurrentLimit {
+				t.Errorf("ConcurrentLimit = %d, want %d", engine.ConcurrentLimit, test.concurrentLimit)
+			}
+			if engine.Timeout != test.timeout {
+				t.Errorf("Timeout = %v, want %v", engine.Timeout, test.timeout)
+			}
+			if got, want := engine.WaitForSelector, test.waitForSelector; (got == nil) != (want == nil) || got != nil && *got != *want {
+				t.Errorf("WaitForSelector = %v, want %v", deref(got), deref(want))
+			}
+			// Resolving the engine of a processor never changes the site engine of the next one
+			if app.siteEngine.ConcurrentLimit != site.ConcurrentLimit || app.siteEngine.Timeout != site.Timeout || app.siteEngine.WaitForSelector != site.WaitForSelector {
+				t.Errorf("site engine changed to %+v", *app.siteEngine)
+			}
+		})
+	}
+}
+
+func TestResolveEngineCopiesBlockedURLs(t *testing.T) {
+	app := newTestCrawler(t, "engine", "http://example.test", Engine{BlockedURLs: []string{"ads"}})
+	site := len(app.siteEngine.BlockedURLs)
+	first := app.resolveEngine(&Engine{BlockedURLs: []string{"first"}})
+	second := app.resolveEngine(&Engine{BlockedURLs: []string{"second"}})
+
+	if got := first.BlockedURLs[len(first.BlockedURLs)-1]; got != "first" {
+		t.Errorf("last BlockedURL of the first engine = %q, want first", got)
+	}
+	if got := second.BlockedURLs[len(second.BlockedURLs)-1]; got != "second" {
+		t.Errorf("last BlockedURL of the second engine = %q, want second", got)
+	}
+	if len(app.siteEngine.BlockedURLs) != site {
+		t.Errorf("BlockedURLs of the site engine = %v", app.siteEngine.BlockedURLs)
+	}
+}
+
+func deref(s *string) string {
+	if s == nil {
+		return "<nil>"
+	}
+	return *s
+}
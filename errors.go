@@ -0,0 +1,156 @@
+package ninjacrawler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/playwright-community/playwright-go"
+)
+
+// errNoProxies is returned when the proxy rotation has no proxy to rotate to.
+var errNoProxies = errors.New("no proxies provided for rotation")
+
+// HTTPStatusError is returned when a page responds with a non successful status code.
+type HTTPStatusError struct {
+	Url        string
+	StatusCode int
+	Status     string
+}
+
+func (e *HTTPStatusError) Error() string {
+	if e.StatusCode == http.StatusNotFound {
+		return fmt.Sprintf("url Not Found: StatusCode %v", e.StatusCode)
+	}
+	return fmt.Sprintf("failed to fetch page: StatusCode: %v, Status: %v", e.StatusCode, e.Status)
+}
+
+// BlockedError is returned when the target site blocks a request, e.g. with a status code listed in Engine.ErrorCodes.
+// The request should be retried with another proxy.
+type BlockedError struct {
+	Url string
+	Err error
+}
+
+func (e *BlockedError) Error() string {
+	return fmt.Sprintf("blocked: %v", e.Err)
+}
+
+func (e *BlockedError) Unwrap() error {
+	return e.Err
+}
+
+// ProxyError is returned when a request fails because of the proxy server.
+// The proxy is stopped and the request should be retried with another proxy.
+type ProxyError struct {
+	Proxy Proxy
+	Err   error
+}
+
+func (e *ProxyError) Error() string {
+	return fmt.Sprintf("proxy %s failed: %v", e.Proxy.Server, e.Err)
+}
+
+func (e *ProxyError) Unwrap() error {
+	return e.Err
+}
+
+// TimeoutError is returned when a navigation does not complete in time.
+type TimeoutError struct {
+	Url string
+	Err error
+}
+
+func (e *TimeoutError) Error() string {
+	return fmt.Sprintf("navigation timeout: %s: %v", e.Url, e.Err)
+}
+
+func (e *TimeoutError) Unwrap() error {
+	return e.Err
+}
+
+// ValidationError is returned when an extracted product does not satisfy the ValidationRules of the processor.
+// Retryable is set when a field matched a blacklisted value, which usually means the page was served incorrectly.
+type ValidationError struct {
+	Url       string
+	Fields    []string
+	Retryable bool
+}
+
+func (e *ValidationError) Error() string {
+	return fmt.Sprintf("Validation failed: %v\n", e.Fields)
+}
+
+// SkippedError is returned when a url is not crawled on purpose, e.g. because robots.txt disallows it.
+type SkippedError struct {
+	Url    string
+	Reason string
+}
+
+func (e *SkippedError) Error() string {
+	return fmt.Sprintf("skipped %s: %s", e.Url, e.Reason)
+}
+
+// NotModifiedError is returned when a conditional request is answered with 304 Not Modified.
+// The page is unchanged since the last crawl, so extraction and submission are skipped.
+type NotModifiedError struct {
+	Url string
+}
+
+func (e *NotModifiedError) Error() string {
+	return fmt.Sprintf("not modified: %s", e.Url)
+}
+
+// IsRetryable reports whether err should be retried with another proxy.
+func IsRetryable(err error) bool {
+	var blockedErr *BlockedError
+	var proxyErr *ProxyError
+	var validationErr *ValidationError
+	switch {
+	case errors.As(err, &blockedErr), errors.As(err, &proxyErr):
+		return true
+	case errors.As(err, &validationErr):
+		return validationErr.Retryable
+	}
+	return false
+}
+
+// IsNotFound reports whether err was caused by a 404 response.
+func IsNotFound(err error) bool {
+	var statusErr *HTTPStatusError
+	return errors.As(err, &statusErr) && statusErr.StatusCode == http.StatusNotFound
+}
+
+// isNotModified reports whether err is a 304 answer to a conditional request, which is not a failure.
+func isNotModified(err error) bool {
+	var notModifiedErr *NotModifiedError
+	return errors.As(err, &notModifiedErr)
+}
+
+// isTimeout reports whether err was caused by a timeout of the http client, the browser or the context.
+func isTimeout(err error) bool {
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return true
+	}
+	return errors.Is(err, context.DeadlineExceeded) || errors.Is(err, playwright.ErrTimeout)
+}
+
+// isProxyFailure reports whether a navigation error was raised by the proxy server rather than the target site.
+func isProxyFailure(err error) bool {
+	for _, msg := range []string{
+		"net::ERR_HTTP_RESPONSE_CODE_FAILURE",
+		"net::ERR_INVALID_AUTH_CREDENTIALS",
+		"Proxy Authentication Required",
+		"Could not connect to proxy server",
+		"NS_ERROR_PROXY_CONNECTION_REFUSED",
+	} {
+		if strings.Contains(err.Error(), msg) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,206 @@
+package ninjacrawler
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Escalation tiers, besides the names of the fetch providers
+const (
+	EscalationProxy        = "proxy"         // http provider through another healthy proxy
+	EscalationPremiumProxy = "premium_proxy" // zenrows with premium_proxy
+	EscalationBrowser      = "browser"       // headless browser of Adapter
+)
+
+// challengeMarkers are found in the challenge pages of the common bot protections.
+var challengeMarkers = []string{
+	"cf-browser-verification",
+	"cf_chl_opt",
+	"<title>Just a moment...</title>",
+	"_Incapsula_Resource",
+	"px-captcha",
+	"captcha-delivery.com",
+	"/_sec/cp_challenge/",
+}
+
+// escalationTiers returns the tiers a static fetch escalates through: Escalation of the engine, or with
+// UsePremiumProxyRetry the provider followed by the zenrows premium proxy. Nil when the fetch does not escalate.
+func escalationTiers(engine *Engine) []string {
+	if len(engine.Escalation) > 0 {
+		return engine.Escalation
+	}
+	if engine.ProviderOption.UsePremiumProxyRetry {
+		return []string{providerName(engine), EscalationPremiumProxy}
+	}
+	return nil
+}
+
+// fetchWithEscalation fetches req and checks the response. With escalation tiers, a blocked tier hands over to the next one,
+// starting at the tier which won the previous crawl of the url. The winning tier is stored on the url collection.
+func (app *Crawler) fetchWithEscalation(ctx context.Context, req *FetchRequest) (*FetchResponse, error) {
+	tiers := escalationTiers(req.Engine)
+	if len(tiers) == 0 {
+		resp, err := app.runFetcher(ctx, req)
+		if err != nil {
+			return nil, err
+		}
+		return resp, app.checkResponse(ctx, req, resp)
+	}
+
+	navigation := crawlRequestFrom(ctx)
+	recorded := ""
+	urlCollection, findErr := app.store.FindUrlCollection(ctx, navigation.Collection, req.Url)
+	if findErr == nil && urlCollection != nil {
+		recorded = urlCollection.FetchTier
+	}
+	first := 0
+	if i := indexOfTier(tiers, recorded); i >= 0 {
+		first = i
+	}
+
+	var (
+		resp    *FetchResponse
+		err     error
+		last    *FetchRequest
+		elapsed time.Duration
+	)
+	for i := first; i < len(tiers); i++ {
+		tierReq, ok := app.tierRequest(req, tiers[i])
+		if !ok {
+			continue
+		}
+		if last != nil {
+			// The previous tier was blocked, its proxy is benched before the next tier picks one
+			app.proxyPool.record(last.Proxy, elapsed, err)
+			app.Logger.Warn("Tier %s blocked for %s: %v, escalating to %s", last.tier, req.Url, err, tierReq.tier)
+			if !*tierReq.Engine.IsDynamic {
+				if err := app.waitForRateLimit(ctx, req.Url); err != nil {
+					return nil, err
+				}
+			}
+		}
+		if tierReq.tier == EscalationProxy {
+			tierReq.Proxy = app.proxyPool.getNext()
+		}
+		start := time.Now()
+		resp, err = app.fetchTier(ctx, tierReq)
+		elapsed = time.Since(start)
+		last = tierReq
+		if !isBlockSignal(err) {
+			break
+		}
+	}
+	if last == nil {
+		return nil, fmt.Errorf("no usable escalation tier in %v", tiers)
+	}
+	// The navigation reports the proxy and provider of the last tier, the earlier ones are recorded above
+	navigation.Proxy = last.Proxy
+	navigation.Provider = providerName(last.Engine)
+	if err != nil {
+		return nil, err
+	}
+	if findErr == nil && last.tier != recorded && (recorded != "" || last.tier != tiers[0]) {
+		if updateErr := app.store.UpdateUrlCollection(ctx, navigation.Collection, req.Url, Map{"fetch_tier": last.tier}); updateErr != nil {
+			app.Logger.Error("Could not store fetch tier of %s: %v", req.Url, updateErr)
+		} else {
+			app.Logger.Info("Fetched %s with tier %s, later crawls start there", req.Url, last.tier)
+		}
+	}
+	return resp, nil
+}
+
+// fetchTier fetches the request of a tier, in the context of a navigation with the engine and proxy of the tier.
+func (app *Crawler) fetchTier(ctx context.Context, req *FetchRequest) (*FetchResponse, error) {
+	navigation := crawlRequestFrom(ctx)
+	tierNavigation := *navigation
+	tierNavigation.Engine = req.Engine
+	tierNavigation.Proxy = req.Proxy
+	tierCtx := withCrawlRequest(ctx, &tierNavigation)
+	defer func() {
+		navigation.Url = tierNavigation.Url // Keep the redirection of the tier
+	}()
+
+	if *req.Engine.IsDynamic {
+		page, err := app.browsers.acquire(tierCtx, req.Engine, req.Proxy)
+		if err != nil {
+			return nil, err
+		}
+		defer app.browsers.release(page)
+		req.Page = page
+	}
+	resp, err := app.runFetcher(tierCtx, req)
+	if err != nil {
+		return nil, err
+	}
+	if err := challengeError(req, resp); err != nil {
+		return resp, err
+	}
+	return resp, app.checkResponse(tierCtx, req, resp)
+}
+
+// tierRequest returns a copy of req fetched by tier, false when tier cannot be used, e.g. proxy without proxy servers.
+func (app *Crawler) tierRequest(req *FetchRequest, tier string) (*FetchRequest, bool) {
+	engine := *req.Engine
+	engine.IsDynamic = Bool(false)
+	tierReq := *req
+	tierReq.Engine = &engine
+	tierReq.tier = tier
+
+	switch tier {
+	case EscalationProxy:
+		if len(engine.ProxyServers) == 0 {
+			return nil, false
+		}
+		engine.Provider = ProviderHttp // The proxy is picked once the blocked proxy is benched
+	case EscalationPremiumProxy:
+		engine.Provider = ProviderZenrows
+		engine.ProviderOption.PremiumProxy = true
+	case EscalationBrowser:
+		engine.IsDynamic = Bool(true)
+	case PlayWrightEngine, RodEngine:
+		engine.IsDynamic = Bool(true)
+		engine.Adapter = String(tier)
+	default:
+		engine.Provider = tier
+	}
+	if _, err := app.fetcherFor(&engine); err != nil {
+		app.Logger.Warn("Skipping escalation tier %s: %v", tier, err)
+		return nil, false
+	}
+	return &tierReq, true
+}
+
+// isBlockSignal reports whether err is a block which the next tier may get around.
+func isBlockSignal(err error) bool {
+	var blockedErr *BlockedError
+	return errors.As(err, &blockedErr)
+}
+
+// challengeError returns a BlockedError when a successful response is a challenge page or has an empty body.
+func challengeError(req *FetchRequest, resp *FetchResponse) error {
+	if resp.StatusCode != 200 {
+		return nil
+	}
+	if len(bytes.TrimSpace(resp.Body)) == 0 {
+		return &BlockedError{Url: req.Url, Err: errors.New("empty body")}
+	}
+	markers := append(append([]string(nil), challengeMarkers...), req.Engine.ChallengeMarkers...)
+	for _, marker := range markers {
+		if bytes.Contains(resp.Body, []byte(marker)) {
+			return &BlockedError{Url: req.Url, Err: fmt.Errorf("challenge page (%s)", marker)}
+		}
+	}
+	return nil
+}
+
+func indexOfTier(values []string, value string) int {
+	for i, v := range values {
+		if v == value {
+			return i
+		}
+	}
+	return -1
+}
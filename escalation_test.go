@@ -0,0 +1,77 @@
+package ninjacrawler
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"slices"
+	"testing"
+)
+
+func TestFetchWithEscalation(t *testing.T) {
+	const url = "http://example.test/c1"
+	tests := []struct {
+		name     string
+		tiers    []string
+		recorded string
+		calls    []string
+		stored   string
+		blocked  bool
+	}{
+		{name: "first tier wins", tiers: []string{"ok", "blocked"}, calls: []string{"ok"}},
+		{name: "blocked error escalates", tiers: []string{"blocked", "ok"}, calls: []string{"blocked", "ok"}, stored: "ok"},
+		{name: "error code escalates", tiers: []string{"forbidden", "ok"}, calls: []string{"forbidden", "ok"}, stored: "ok"},
+		{name: "challenge page escalates", tiers: []string{"challenge", "ok"}, calls: []string{"challenge", "ok"}, stored: "ok"},
+		{name: "empty body escalates", tiers: []string{"empty", "ok"}, calls: []string{"empty", "ok"}, stored: "ok"},
+		{name: "other errors do not escalate", tiers: []string{"failing", "ok"}, calls: []string{"failing"}},
+		{name: "unknown provider is skipped", tiers: []string{"missing", "ok"}, calls: []string{"ok"}, stored: "ok"},
+		{name: "recorded tier first", tiers: []string{"blocked", "challenge", "ok"}, recorded: "ok", calls: []string{"ok"}, stored: "ok"},
+		{name: "recorded tier blocked", tiers: []string{"blocked", "challenge", "ok"}, recorded: "challenge", calls: []string{"challenge", "ok"}, stored: "ok"},
+		{name: "unknown recorded tier", tiers: []string{"ok", "blocked"}, recorded: "zenrows", calls: []string{"ok"}, stored: "ok"},
+		{name: "every tier blocked", tiers: []string{"blocked", "challenge"}, recorded: "blocked", calls: []string{"blocked", "challenge"}, stored: "blocked", blocked: true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			app := newTestCrawler(t, "escalation", "http://example.test")
+			var calls []string
+			fetcher := func(name string, resp *FetchResponse, err error) {
+				app.RegisterFetcher(name, FetcherFunc(func(ctx context.Context, req *FetchRequest) (*FetchResponse, error) {
+					calls = append(calls, name)
+					return resp, err
+				}))
+			}
+			fetcher("ok", &FetchResponse{StatusCode: http.StatusOK, Body: []byte("<html><body>ok</body></html>")}, nil)
+			fetcher("blocked", nil, &BlockedError{Url: url, Err: errors.New("captcha")})
+			fetcher("forbidden", &FetchResponse{StatusCode: http.StatusForbidden, Body: []byte("denied")}, nil)
+			fetcher("challenge", &FetchResponse{StatusCode: http.StatusOK, Body: []byte("<html><head><title>Just a moment...</title></head></html>")}, nil)
+			fetcher("empty", &FetchResponse{StatusCode: http.StatusOK, Body: []byte(" \n")}, nil)
+			fetcher("failing", nil, errors.New("connection reset"))
+
+			ctx := context.Background()
+			if err := app.store.InsertUrlCollections(ctx, "categories", []UrlCollection{{Url: url, Parent: "http://example.test", FetchTier: test.recorded}}); err != nil {
+				t.Fatal(err)
+			}
+			engine := app.resolveEngine(&Engine{Escalation: test.tiers, ErrorCodes: []int{http.StatusForbidden}})
+			navigation := &crawlRequest{Collection: "categories", Url: url, DocumentUrl: url, Engine: engine}
+			_, err := app.fetchWithEscalation(withCrawlRequest(ctx, navigation), &FetchRequest{Url: url, Engine: engine})
+
+			var blockedErr *BlockedError
+			if test.blocked != errors.As(err, &blockedErr) {
+				t.Errorf("fetch error = %v, want blocked %v", err, test.blocked)
+			}
+			if !slices.Equal(calls, test.calls) {
+				t.Errorf("tiers fetched = %v, want %v", calls, test.calls)
+			}
+			if provider := calls[len(calls)-1]; navigation.Provider != provider {
+				t.Errorf("navigation provider = %q, want %q", navigation.Provider, provider)
+			}
+			urlCollection, err := app.store.FindUrlCollection(ctx, "categories", url)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if urlCollection.FetchTier != test.stored {
+				t.Errorf("stored fetch tier = %q, want %q", urlCollection.FetchTier, test.stored)
+			}
+		})
+	}
+}
@@ -2,8 +2,8 @@ package ninjacrawler
 
 import (
 	"fmt"
-	"github.com/go-rod/rod"
-	"github.com/playwright-community/playwright-go"
+	"go.opentelemetry.io/otel/attribute"
+	semconv "go.opentelemetry.io/otel/semconv/v1.24.0"
 )
 
 func (app *Crawler) extract(page interface{}, processorConfig ProcessorConfig, ctx CrawlerContext) error {
@@ -22,9 +22,9 @@ func (app *Crawler) extract(page interface{}, processorConfig ProcessorConfig, c
 				continue
 			}
 		}
-		app.insert(processorConfig.Entity, collections, ctx.UrlCollection.Url)
+		app.insert(ctx.Context(), processorConfig.Entity, inheritSessionKey(collections, ctx.UrlCollection), ctx.UrlCollection.Url)
 		if !processorConfig.Preference.DoNotMarkAsComplete {
-			err := app.markAsComplete(ctx.UrlCollection.Url, processorConfig.OriginCollection)
+			err := app.markAsComplete(ctx.Context(), ctx.UrlCollection.Url, processorConfig.OriginCollection)
 			if err != nil {
 				return err
 			}
@@ -40,7 +40,7 @@ func (app *Crawler) extract(page interface{}, processorConfig ProcessorConfig, c
 			}
 			if currentPageUrl != "" && currentPageUrl != ctx.UrlCollection.Url {
 				shouldMarkAsComplete = false
-				currentPageErr := app.SyncCurrentPageUrl(ctx.UrlCollection.Url, currentPageUrl, processorConfig.OriginCollection)
+				currentPageErr := app.SyncCurrentPageUrlContext(ctx.Context(), ctx.UrlCollection.Url, currentPageUrl, processorConfig.OriginCollection)
 				if currentPageErr != nil {
 					app.Logger.Fatal(currentPageErr.Error())
 					return
@@ -48,17 +48,17 @@ func (app *Crawler) extract(page interface{}, processorConfig ProcessorConfig, c
 			} else {
 				shouldMarkAsComplete = true
 			}
-			app.insert(processorConfig.Entity, collections, ctx.UrlCollection.Url)
+			app.insert(ctx.Context(), processorConfig.Entity, inheritSessionKey(collections, ctx.UrlCollection), ctx.UrlCollection.Url)
 		})
 		if handleErr != nil {
-			markAsError := app.MarkAsError(ctx.UrlCollection.Url, processorConfig.OriginCollection, handleErr.Error())
+			markAsError := app.MarkAsErrorContext(ctx.Context(), ctx.UrlCollection.Url, processorConfig.OriginCollection, handleErr.Error())
 			if markAsError != nil {
 				return markAsError
 			}
 			app.Logger.Error(handleErr.Error())
 		} else {
 			if !processorConfig.Preference.DoNotMarkAsComplete && shouldMarkAsComplete {
-				err := app.markAsComplete(ctx.UrlCollection.Url, processorConfig.OriginCollection)
+				err := app.markAsComplete(ctx.Context(), ctx.UrlCollection.Url, processorConfig.OriginCollection)
 				if err != nil {
 					return err
 				}
@@ -75,10 +75,10 @@ func (app *Crawler) extract(page interface{}, processorConfig ProcessorConfig, c
 				continue
 			}
 		}
-		app.insert(processorConfig.Entity, collections, ctx.UrlCollection.Url)
+		app.insert(ctx.Context(), processorConfig.Entity, inheritSessionKey(collections, ctx.UrlCollection), ctx.UrlCollection.Url)
 
 		if !processorConfig.Preference.DoNotMarkAsComplete {
-			err := app.markAsComplete(ctx.UrlCollection.Url, processorConfig.OriginCollection)
+			err := app.markAsComplete(ctx.Context(), ctx.UrlCollection.Url, processorConfig.OriginCollection)
 			if err != nil {
 				return err
 			}
@@ -89,7 +89,7 @@ func (app *Crawler) extract(page interface{}, processorConfig ProcessorConfig, c
 		handleErr := v(ctx, func(collections []ProductDetailSelector, currentPageUrl string) {
 			if currentPageUrl != "" && currentPageUrl != ctx.UrlCollection.Url {
 				shouldMarkAsComplete = false
-				currentPageErr := app.SyncCurrentPageUrl(ctx.UrlCollection.Url, currentPageUrl, processorConfig.OriginCollection)
+				currentPageErr := app.SyncCurrentPageUrlContext(ctx.Context(), ctx.UrlCollection.Url, currentPageUrl, processorConfig.OriginCollection)
 				if currentPageErr != nil {
 					app.Logger.Fatal(currentPageErr.Error())
 					return
@@ -108,14 +108,14 @@ func (app *Crawler) extract(page interface{}, processorConfig ProcessorConfig, c
 			}
 		})
 		if handleErr != nil {
-			markAsError := app.MarkAsError(ctx.UrlCollection.Url, processorConfig.OriginCollection, handleErr.Error())
+			markAsError := app.MarkAsErrorContext(ctx.Context(), ctx.UrlCollection.Url, processorConfig.OriginCollection, handleErr.Error())
 			if markAsError != nil {
 				return markAsError
 			}
 			app.Logger.Error(handleErr.Error())
 		} else {
 			if !processorConfig.Preference.DoNotMarkAsComplete && shouldMarkAsComplete {
-				err := app.markAsComplete(ctx.UrlCollection.Url, processorConfig.OriginCollection)
+				err := app.markAsComplete(ctx.Context(), ctx.UrlCollection.Url, processorConfig.OriginCollection)
 				if err != nil {
 					return err
 				}
@@ -128,7 +128,7 @@ func (app *Crawler) extract(page interface{}, processorConfig ProcessorConfig, c
 			return err
 		}
 		if !processorConfig.Preference.DoNotMarkAsComplete {
-			errM := app.markAsComplete(ctx.UrlCollection.Url, processorConfig.OriginCollection)
+			errM := app.markAsComplete(ctx.Context(), ctx.UrlCollection.Url, processorConfig.OriginCollection)
 			if errM != nil {
 				return errM
 			}
@@ -140,7 +140,7 @@ func (app *Crawler) extract(page interface{}, processorConfig ProcessorConfig, c
 			return err
 		}
 		if !processorConfig.Preference.DoNotMarkAsComplete {
-			errM := app.markAsComplete(ctx.UrlCollection.Url, processorConfig.OriginCollection)
+			errM := app.markAsComplete(ctx.Context(), ctx.UrlCollection.Url, processorConfig.OriginCollection)
 			if errM != nil {
 				return errM
 			}
@@ -153,32 +153,48 @@ func (app *Crawler) extract(page interface{}, processorConfig ProcessorConfig, c
 }
 
 func (app *Crawler) validateProductDetail(res *ProductDetail, processorConfig ProcessorConfig, ctx CrawlerContext) error {
-	invalidFields, unknownFields := validateRequiredFields(res, processorConfig.Preference.ValidationRules)
+	_, validateSpan := app.startSpan(ctx.Context(), "validate", semconv.URLFull(res.Url))
+	invalidFields, unknownFields, blacklisted := validateRequiredFields(res, processorConfig.Preference.ValidationRules)
+	validateSpan.SetAttributes(attribute.StringSlice("invalid_fields", invalidFields))
+	validateSpan.End()
 	if len(unknownFields) > 0 {
 		return fmt.Errorf("unknown fields provided: %v", unknownFields)
 	}
 	if len(invalidFields) > 0 {
-		msg := fmt.Sprintf("Validation failed: %v\n", invalidFields)
+		validationErr := &ValidationError{Url: ctx.UrlCollection.Url, Fields: invalidFields, Retryable: blacklisted}
+		msg := validationErr.Error()
 		html, _ := ctx.Document.Html()
 		app.Logger.Html(html, ctx.UrlCollection.Url, msg, "validation")
 		var err error
-		if *app.engine.IgnoreRetryOnValidation {
-			err = app.MarkAsMaxErrorAttempt(ctx.UrlCollection.Url, processorConfig.OriginCollection, msg)
+		if *app.engineFor(ctx.Context()).IgnoreRetryOnValidation {
+			err = app.MarkAsMaxErrorAttemptContext(ctx.Context(), ctx.UrlCollection.Url, processorConfig.OriginCollection, msg)
 		} else {
-			err = app.MarkAsError(ctx.UrlCollection.Url, processorConfig.OriginCollection, msg)
+			err = app.MarkAsErrorContext(ctx.Context(), ctx.UrlCollection.Url, processorConfig.OriginCollection, msg)
 		}
 		if err != nil {
 			return err
 		}
-		return fmt.Errorf(msg)
+		return validationErr
 	}
 
-	app.saveProductDetail(processorConfig.Entity, res)
+	storeCtx, storeSpan := app.startSpan(ctx.Context(), "store", semconv.URLFull(res.Url), attribute.String("collection", processorConfig.Entity))
+	change := app.trackProductChange(storeCtx, processorConfig.Entity, res)
+	app.saveProductDetail(storeCtx, processorConfig.Entity, res)
+	storeSpan.End()
+	if change == productUnchanged {
+		app.Logger.Debug("Product unchanged, skipping submission: %s", res.Url)
+		return nil
+	}
 	if !app.isLocalEnv {
+		_, submitSpan := app.startSpan(ctx.Context(), "submit", semconv.URLFull(res.Url))
 		err := app.submitProductData(res)
+		endSpan(submitSpan, err)
 		if err != nil {
+			// Forget the hash, so the product is submitted again by the next crawl
+			res.ContentHash = ""
+			app.saveProductDetail(ctx.Context(), processorConfig.Entity, res)
 			app.Logger.Error("Failed to submit product data to API Server: %v", err)
-			errM := app.MarkAsError(ctx.UrlCollection.Url, processorConfig.OriginCollection, err.Error())
+			errM := app.MarkAsErrorContext(ctx.Context(), ctx.UrlCollection.Url, processorConfig.OriginCollection, err.Error())
 			if errM != nil {
 				return errM
 			}
@@ -188,24 +204,9 @@ func (app *Crawler) validateProductDetail(res *ProductDetail, processorConfig Pr
 	return nil
 }
 
+// closePages closes a page of openPages and hands its browser back to the pool.
 func (app *Crawler) closePages(pageInterFace interface{}) {
-	if *app.engine.IsDynamic {
-		if *app.engine.Adapter == PlayWrightEngine {
-			var page playwright.Page
-			page = pageInterFace.(playwright.Page)
-			err := page.Close()
-			if err != nil {
-				app.Logger.Error("Failed to close page: %v", err)
-				return
-			}
-		} else {
-			var page *rod.Page
-			page = pageInterFace.(*rod.Page)
-			err := page.Close()
-			if err != nil {
-				app.Logger.Error("Failed to close page: %v", err)
-				return
-			}
-		}
+	if pageInterFace != nil {
+		app.browsers.release(pageInterFace)
 	}
 }
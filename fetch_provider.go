@@ -0,0 +1,172 @@
+package ninjacrawler
+
+import (
+	"context"
+	"fmt"
+	"github.com/PuerkitoBio/goquery"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+)
+
+const (
+	ProviderHttp    = "http"
+	ProviderZenrows = "zenrows"
+)
+
+// Fetcher downloads a url for the crawler. The built-in fetchers are registered as "http", "zenrows", "playwright" and "rod",
+// custom ones with RegisterFetcher. Engine.Provider chooses the fetcher of static crawls, dynamic crawls use the one of Engine.Adapter.
+//
+// Fetch returns an error only when no response was received, use the typed errors (TimeoutError, ProxyError) so retries
+// and proxy health work. Unsuccessful status codes are returned in the response, the crawler turns them into errors.
+type Fetcher interface {
+	Fetch(ctx context.Context, req *FetchRequest) (*FetchResponse, error)
+}
+
+// FetcherFunc adapts a function to a Fetcher.
+type FetcherFunc func(ctx context.Context, req *FetchRequest) (*FetchResponse, error)
+
+func (f FetcherFunc) Fetch(ctx context.Context, req *FetchRequest) (*FetchResponse, error) {
+	return f(ctx, req)
+}
+
+// FetchRequest is the url to fetch and the options of the fetch.
+type FetchRequest struct {
+	Url    string
+	Proxy  Proxy       // Proxy to send the request through, empty for none
+	Engine *Engine     // Engine of the navigation, with the provider options
+	Header http.Header // Extra request headers, e.g. the validators of a conditional request
+	Page   interface{} // playwright.Page or *rod.Page opened by the crawler, for the browser fetchers
+
+	client *http.Client
+	tier   string // Escalation tier of the request
+}
+
+// FetchResponse is the outcome of a fetch.
+type FetchResponse struct {
+	Body       []byte
+	StatusCode int
+	Status     string
+	Header     http.Header
+	FinalUrl   string        // Url after redirections, the requested url when empty
+	Duration   time.Duration // Time the fetch took, measured by the crawler when zero
+	Page       interface{}   // Page navigated by the browser fetchers
+
+	document *goquery.Document // Document already parsed by the browser fetchers
+}
+
+// browserResponse returns the response of a successful browser navigation.
+func browserResponse(page interface{}, finalUrl string, document *goquery.Document) *FetchResponse {
+	html, _ := document.Html()
+	return &FetchResponse{
+		Body:       []byte(html),
+		StatusCode: http.StatusOK,
+		FinalUrl:   finalUrl,
+		Page:       page,
+		document:   document,
+	}
+}
+
+// fetcherRegistry holds the fetchers of a crawler by provider name.
+type fetcherRegistry struct {
+	fetchers map[string]Fetcher
+	mu       sync.RWMutex
+}
+
+func newFetcherRegistry(app *Crawler) *fetcherRegistry {
+	return &fetcherRegistry{
+		fetchers: map[string]Fetcher{
+			ProviderHttp:     &httpFetcher{app: app},
+			ProviderZenrows:  &zenrowsFetcher{app: app},
+			PlayWrightEngine: &playwrightFetcher{app: app},
+			RodEngine:        &rodFetcher{app: app},
+		},
+	}
+}
+
+func (r *fetcherRegistry) set(name string, fetcher Fetcher) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.fetchers[name] = fetcher
+}
+
+func (r *fetcherRegistry) get(name string) (Fetcher, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	fetcher, ok := r.fetchers[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown fetch provider %q", name)
+	}
+	return fetcher, nil
+}
+
+// RegisterFetcher registers fetcher under name, replacing the fetcher registered before under that name.
+// Choose it with Engine.Provider, for the whole crawler or per ProcessorConfig.
+func (app *Crawler) RegisterFetcher(name string, fetcher Fetcher) {
+	app.fetchers.set(name, fetcher)
+}
+
+// providerName returns the name of the fetcher of engine: the browser adapter for dynamic crawls, the provider otherwise.
+func providerName(engine *Engine) string {
+	if *engine.IsDynamic {
+		return *engine.Adapter
+	}
+	if engine.Provider == "" {
+		return ProviderHttp
+	}
+	return engine.Provider
+}
+
+func (app *Crawler) fetcherFor(engine *Engine) (Fetcher, error) {
+	return app.fetchers.get(providerName(engine))
+}
+
+// runFetcher fetches req with the fetcher of its engine, filling in the defaults of the response.
+func (app *Crawler) runFetcher(ctx context.Context, req *FetchRequest) (*FetchResponse, error) {
+	fetcher, err := app.fetcherFor(req.Engine)
+	if err != nil {
+		return nil, err
+	}
+	start := time.Now()
+	resp, err := fetcher.Fetch(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.Duration == 0 {
+		resp.Duration = time.Since(start)
+	}
+	if resp.FinalUrl == "" {
+		resp.FinalUrl = req.Url
+	}
+	if resp.Status == "" {
+		resp.Status = fmt.Sprintf("%d %s", resp.StatusCode, http.StatusText(resp.StatusCode))
+	}
+	if resp.Header == nil {
+		resp.Header = http.Header{}
+	}
+	return resp, nil
+}
+
+// checkResponse records the status code and redirection of a static response, and turns unsuccessful status codes into errors.
+func (app *Crawler) checkResponse(ctx context.Context, req *FetchRequest, resp *FetchResponse) error {
+	_ = app.updateStatusCode(ctx, req.Url, resp.StatusCode)
+	// Check if a redirect occurred
+	if resp.FinalUrl != req.Url && *req.Engine.TrackRedirection {
+		finalUrl, _ := url.Parse(resp.FinalUrl)
+		originalUrl, _ := url.Parse(req.Url)
+		if finalUrl != nil && originalUrl != nil && finalUrl.Host != originalUrl.Host {
+			crawlRequestFrom(ctx).Url = resp.FinalUrl
+			_ = app.updateRedirection(ctx, req.Url, resp.FinalUrl)
+			app.Logger.Warn(fmt.Sprintf("Redirection detected: %s -> %s", req.Url, resp.FinalUrl))
+		}
+	}
+	if resp.StatusCode == http.StatusNotModified {
+		return &NotModifiedError{Url: req.Url}
+	}
+	if resp.StatusCode != http.StatusOK {
+		return app.handleHttpError(ctx, resp.StatusCode, resp.Status, req.Url, resp.Body)
+	}
+	app.updateValidators(ctx, req.Url, resp.Header)
+	return nil
+}
@@ -1,14 +1,16 @@
 package ninjacrawler
 
 import (
+	"bytes"
 	"context"
-	"fmt"
 	"github.com/PuerkitoBio/goquery"
 	"github.com/go-rod/rod"
 	"github.com/playwright-community/playwright-go"
+	"go.opentelemetry.io/otel/attribute"
+	"time"
 )
 
-func (app *Crawler) handleCrawlWorker(page interface{}, processorConfig ProcessorConfig, urlCollection UrlCollection, proxy Proxy) (*CrawlerContext, error) {
+func (app *Crawler) handleCrawlWorker(ctx context.Context, page interface{}, processorConfig ProcessorConfig, urlCollection UrlCollection, proxy Proxy) (*CrawlerContext, error) {
 
 	crawlableUrl := urlCollection.Url
 	if urlCollection.ApiUrl != "" {
@@ -25,38 +27,53 @@ func (app *Crawler) handleCrawlWorker(page interface{}, processorConfig Processo
 	}
 
 	// Add a timeout for the navigation process
-	ctx, cancel := context.WithTimeout(context.Background(), app.engine.Timeout*2)
+	navCtx, cancel := context.WithTimeout(ctx, app.engineFor(ctx).Timeout*2)
 	defer cancel()
 
-	navigationContext, navErr := app.navigateTo(ctx, page, crawlableUrl, processorConfig.OriginCollection, navigateToApi, proxy)
+	parent := crawlRequestFrom(ctx)
+	navCtx = withCrawlRequest(navCtx, &crawlRequest{DocumentUrl: urlCollection.Url, Engine: parent.Engine, Session: parent.Session})
+	navigationContext, navErr := app.navigateTo(navCtx, page, crawlableUrl, processorConfig.OriginCollection, navigateToApi, proxy)
 	if navErr != nil {
 		return nil, navErr
 	}
 
-	crawlerCtx := app.getCrawlerCtx(navigationContext)
+	crawlerCtx := app.getCrawlerCtx(app.engineFor(ctx), navigationContext)
 	crawlerCtx.UrlCollection = urlCollection
+	crawlerCtx.ctx = app.withProxySession(ctx, urlCollection)
 	if navigateToApi {
 		crawlerCtx.ApiResponse = navigationContext.Response.(Map)
 	}
 	return crawlerCtx, nil
 }
 
-func (app *Crawler) getCrawlerCtx(navigationContext *NavigationContext) *CrawlerContext {
+func (app *Crawler) getCrawlerCtx(engine *Engine, navigationContext *NavigationContext) *CrawlerContext {
 	crawlerCtx := &CrawlerContext{
 		App:      app,
 		Document: navigationContext.Document,
 	}
 
-	if *app.engine.IsDynamic {
-		if *app.engine.Adapter == PlayWrightEngine {
-			crawlerCtx.Page = navigationContext.Response.(playwright.Page)
-		} else {
-			crawlerCtx.RodPage = navigationContext.Response.(*rod.Page)
+	if *engine.IsDynamic {
+		switch page := navigationContext.Response.(type) {
+		case playwright.Page:
+			crawlerCtx.Page = page
+		case *rod.Page:
+			crawlerCtx.RodPage = page
 		}
 	}
 	return crawlerCtx
 }
 func (app *Crawler) navigateTo(ctx context.Context, page interface{}, crawlableUrl string, origin string, navigateToApi bool, currentProxy Proxy) (*NavigationContext, error) {
+	if !app.isAllowedByRobots(crawlableUrl) {
+		app.Logger.Warn("[SKIP] %s: %s", skipReasonRobotsTxt, crawlableUrl)
+		return nil, &SkippedError{Url: crawlableUrl, Reason: skipReasonRobotsTxt}
+	}
+	engine := app.engineFor(ctx)
+	parent := crawlRequestFrom(ctx)
+	ctx = withCrawlRequest(ctx, &crawlRequest{Collection: origin, Url: crawlableUrl, DocumentUrl: parent.DocumentUrl, Proxy: currentProxy, Engine: engine, Session: parent.Session})
+	start := time.Now()
+	provider := fetchProvider(engine, navigateToApi)
+	ctx, span := app.startSpan(ctx, "navigate", append(urlAttributes(crawlableUrl, origin, currentProxy),
+		attribute.String("provider", provider))...)
 	// Create a channel to capture navigation result
 	resultChan := make(chan navigationResult, 1)
 
@@ -64,28 +81,27 @@ func (app *Crawler) navigateTo(ctx context.Context, page interface{}, crawlableU
 		var (
 			err      error
 			doc      *goquery.Document
-			pwPage   playwright.Page
-			rdPage   *rod.Page
 			response interface{}
 		)
+		logger := app.Logger.With("collection", origin, "url", crawlableUrl)
 		if currentProxy.Server != "" {
-			app.Logger.Info("Crawling %s: %s using Proxy %s", origin, crawlableUrl, currentProxy.Server)
-		} else {
-			app.Logger.Info("Crawling %s: %s", origin, crawlableUrl)
+			logger = logger.With("proxy", currentProxy.Server)
 		}
+		logger.Info("Crawling")
 		// Actual navigation logic
-		if *app.engine.IsDynamic && page != nil {
-			if *app.engine.Adapter == PlayWrightEngine {
-				pwPage, doc, err = app.NavigateToURL(page, crawlableUrl, currentProxy)
-				response = pwPage
-			} else if *app.engine.Adapter == RodEngine {
-				rdPage, doc, err = app.NavigateRodURL(page, crawlableUrl, currentProxy)
-				response = rdPage
+		if *engine.IsDynamic && page != nil {
+			var fetchResp *FetchResponse
+			fetchResp, err = app.runFetcher(ctx, &FetchRequest{Url: crawlableUrl, Proxy: currentProxy, Engine: engine, Page: page})
+			if err == nil {
+				doc, response = fetchResp.document, fetchResp.Page
+				if doc == nil {
+					doc, err = goquery.NewDocumentFromReader(bytes.NewReader(fetchResp.Body))
+				}
 			}
 		} else if navigateToApi {
-			response, err = app.NavigateToApiURL(app.httpClient, crawlableUrl, currentProxy)
+			response, err = app.navigateToApiURL(ctx, app.httpClient, crawlableUrl, currentProxy)
 		} else {
-			doc, err = app.NavigateToStaticURL(app.httpClient, crawlableUrl, currentProxy)
+			doc, err = app.navigateToStaticURL(ctx, app.httpClient, crawlableUrl, currentProxy)
 			response = app.httpClient
 		}
 
@@ -101,13 +117,25 @@ func (app *Crawler) navigateTo(ctx context.Context, page interface{}, crawlableU
 	// Wait for either navigation completion or context timeout
 	select {
 	case result := <-resultChan:
+		// The proxy and provider of the request, changed when the fetch escalated to another tier
+		navigation := crawlRequestFrom(ctx)
+		if navigation.Provider != "" {
+			provider = navigation.Provider
+		}
+		app.observeNavigation(origin, provider, navigation.Proxy, start, result.Err)
+		endSpan(span, result.Err)
 		if result.Err != nil {
-			app.Logger.Error("Error during navigation to %s: %v", crawlableUrl, result.Err)
+			if !isNotModified(result.Err) {
+				app.Logger.Error("Error during navigation to %s: %v", crawlableUrl, result.Err)
+			}
 			return nil, result.Err
 		}
 		return result.NavigationContext, nil
 	case <-ctx.Done():
-		return nil, fmt.Errorf("navigation timeout: %s", crawlableUrl)
+		timeoutErr := &TimeoutError{Url: crawlableUrl, Err: ctx.Err()}
+		app.observeNavigation(origin, provider, currentProxy, start, timeoutErr)
+		endSpan(span, timeoutErr)
+		return nil, timeoutErr
 	}
 }
 
@@ -34,20 +34,25 @@ func isLocalEnv(env string) bool {
 
 // processDocument processes the document based on the urlSelector type
 func (app *Crawler) processDocument(doc *goquery.Document, selector UrlSelector, collection UrlCollection) []UrlCollection {
+	if app.hasNofollowMeta(doc) {
+		app.Logger.Warn("[SKIP] robots meta nofollow, links not followed: %s", collection.Url)
+		return nil
+	}
+	var items []UrlCollection
 	if selector.SingleResult {
 		// Process a single result
-		return app.processSingleResult(doc, selector, collection)
+		items = app.processSingleResult(doc, selector, collection)
 	} else {
 		// Process multiple results
-		var items []UrlCollection
-
 		doc.Find(selector.Selector).Each(func(i int, selection *goquery.Selection) {
 			item := app.processSelection(selection, selector, collection)
 			items = append(items, item...)
 		})
-
-		return items
 	}
+	app.observeStructure(map[string]float64{
+		fmt.Sprintf("urls:%s %s", selector.Selector, selector.FindSelector): float64(len(items)),
+	})
+	return items
 }
 
 // processSingleResult processes a single result based on the selector
@@ -355,13 +360,13 @@ func (app *Crawler) GetQueryEscapeFullUrl(urlStr string) string {
 	return encodedURL
 }
 
-// shouldBlockResource checks if a resource should be blocked based on its type and URL.
-func (app *Crawler) shouldBlockResource(resourceType string, url string) bool {
+// shouldBlockResource checks if a resource should be blocked based on its type and the BlockedURLs of engine.
+func (app *Crawler) shouldBlockResource(engine *Engine, resourceType string, url string) bool {
 	if resourceType == "image" || resourceType == "font" {
 		return true
 	}
 
-	for _, blockedURL := range app.engine.BlockedURLs {
+	for _, blockedURL := range engine.BlockedURLs {
 		if strings.Contains(url, blockedURL) {
 			return true
 		}
@@ -557,12 +562,24 @@ func (app *Crawler) GetUrlFileExtension(urlString string) string {
 
 func (app *Crawler) HandleThrottling(attempt, StatusCode int) {
 	if attempt > 0 {
-		sleepDuration := time.Duration(app.engine.RetrySleepDuration) * time.Minute * time.Duration(attempt)
+		sleepDuration := time.Duration(app.currentEngine().RetrySleepDuration) * time.Minute * time.Duration(attempt)
 		app.Logger.Debug("Sleeping for %s StatusCode: %d", sleepDuration, StatusCode)
 		time.Sleep(sleepDuration)
 	}
 }
 
+// sleepContext sleeps for d, returning early with the error of ctx when it is done.
+func sleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
 type comparableType interface {
 	int | string
 }
@@ -610,6 +627,11 @@ func (app *Crawler) SaveHtml(data interface{}, urlString string) error {
 }
 
 func (app *Crawler) SendHtmlToBigquery(data interface{}, urlString string) error {
+	return app.SendHtmlToBigqueryContext(context.Background(), data, urlString)
+}
+
+// SendHtmlToBigqueryContext is SendHtmlToBigquery with the context of the navigation, a failure is recorded on its url collection.
+func (app *Crawler) SendHtmlToBigqueryContext(ctx context.Context, data interface{}, urlString string) error {
 	htmlContent, err := app.GetHtml(data)
 	if err != nil {
 		return fmt.Errorf("failed to get html %s", err.Error())
@@ -618,7 +640,7 @@ func (app *Crawler) SendHtmlToBigquery(data interface{}, urlString string) error
 	if metadata.OnGCE() {
 		bigqueryErr := app.sendHtmlToBigquery(htmlContent, urlString)
 		if bigqueryErr != nil {
-			bigErr := app.markAsBigQueryFailed(urlString, bigqueryErr.Error())
+			bigErr := app.markAsBigQueryFailed(ctx, urlString, bigqueryErr.Error())
 			if bigErr != nil {
 				return bigErr
 			}
@@ -830,45 +852,44 @@ func (app *Crawler) stopProxy(proxy Proxy, errStr string) error {
 	return nil
 }
 
-func (app *Crawler) handleHttpError(statusCode int, statusText string, url string, data interface{}) error {
-
-	msg := fmt.Sprintf("failed to fetch page: StatusCode: %v, Status: %v", statusCode, statusText)
+func (app *Crawler) handleHttpError(ctx context.Context, statusCode int, statusText string, url string, data interface{}) error {
+	statusErr := &HTTPStatusError{Url: url, StatusCode: statusCode, Status: statusText}
 
 	// Handle 404 error
 	if statusCode == http.StatusNotFound {
-		_ = app.MarkAsMaxErrorAttempt(url, app.CurrentCollection, "Url Not Found")
-		return fmt.Errorf("url Not Found: StatusCode %v", statusCode)
+		_ = app.MarkAsMaxErrorAttemptContext(ctx, url, crawlRequestFrom(ctx).Collection, "Url Not Found")
+		return statusErr
 	}
 
+	var err error = statusErr
 	// Handle retryable error codes
-	if inArray(app.engine.ErrorCodes, statusCode) {
-		msg = fmt.Sprintf("isRetryable: StatusCode: %v, Status: %v", statusCode, statusText)
-		app.Logger.Error(msg)
-		app.Logger.Debug("Got Blocked at URL: %s Error: %v\n", app.CurrentUrl, msg)
+	if inArray(app.engineFor(ctx).ErrorCodes, statusCode) {
+		err = &BlockedError{Url: url, Err: statusErr}
+		app.Logger.Error(err.Error())
+		app.Logger.Debug("Got Blocked at URL: %s Error: %v\n", crawlRequestFrom(ctx).Url, err)
 	} else {
-		app.Logger.Debug("Http Error URL: %s Error: %v\n", url, msg)
+		app.Logger.Debug("Http Error URL: %s Error: %v\n", url, err)
 	}
-	htmlStr, err := app.GetHtml(data)
-	if err != nil {
-		return err
+	htmlStr, htmlErr := app.GetHtml(data)
+	if htmlErr != nil {
+		return fmt.Errorf("%w (page not readable: %v)", err, htmlErr)
 	}
-	app.Logger.Html(htmlStr, url, msg)
-	return fmt.Errorf(msg)
+	app.Logger.Html(htmlStr, url, err.Error())
+	return err
 }
-func (app *Crawler) handleProxyError(proxy Proxy, err error) (*goquery.Document, error) {
-	if strings.Contains(err.Error(), "net::ERR_HTTP_RESPONSE_CODE_FAILURE") ||
-		strings.Contains(err.Error(), "net::ERR_INVALID_AUTH_CREDENTIALS") ||
-		strings.Contains(err.Error(), "Proxy Authentication Required") ||
-		strings.Contains(err.Error(), "Could not connect to proxy server") ||
-		strings.Contains(err.Error(), "NS_ERROR_PROXY_CONNECTION_REFUSED") {
+func (app *Crawler) handleProxyError(ctx context.Context, proxy Proxy, err error) (*goquery.Document, error) {
+	if isProxyFailure(err) {
 		stopErr := app.stopProxy(proxy, err.Error())
 		if stopErr != nil {
 			return nil, stopErr
 		}
-		app.syncProxies()
-		return nil, fmt.Errorf("isRetryable: Unexpected Error: %s", err.Error())
+		// The proxy pool benches the failed proxy, the proxy list stays as synced at start
+		return nil, &ProxyError{Proxy: proxy, Err: err}
+	}
+	if isTimeout(err) {
+		return nil, &TimeoutError{Url: crawlRequestFrom(ctx).Url, Err: err}
 	}
-	return nil, fmt.Errorf("failed to navigate %s", err.Error())
+	return nil, fmt.Errorf("failed to navigate %w", err)
 }
 func ensureScheme(rawUrl string) (string, error) {
 	// Check if the URL starts with a scheme (http://, https://, etc.)
@@ -887,16 +908,16 @@ func ensureScheme(rawUrl string) (string, error) {
 }
 
 func (app *Crawler) getCurrentProxy() Proxy {
-	locker.Lock()
-	defer locker.Unlock()
+	app.proxyMu.Lock()
+	defer app.proxyMu.Unlock()
 	proxy := Proxy{}
-	if len(app.engine.ProxyServers) == 0 && app.engine.ProxyStrategy == ProxyStrategyRotation {
+	if len(app.currentEngine().ProxyServers) == 0 && app.currentEngine().ProxyStrategy == ProxyStrategyRotation {
 		app.Logger.Fatal("No Active proxy servers found")
 	}
 
-	if len(app.engine.ProxyServers) > 0 {
-		index := atomic.LoadInt32(&app.CurrentProxyIndex) % int32(len(app.engine.ProxyServers))
-		proxy = app.engine.ProxyServers[index]
+	if len(app.currentEngine().ProxyServers) > 0 {
+		index := atomic.LoadInt32(&app.CurrentProxyIndex) % int32(len(app.currentEngine().ProxyServers))
+		proxy = app.currentEngine().ProxyServers[index]
 	}
 	return proxy
 }
@@ -5,36 +5,58 @@ import (
 	"cloud.google.com/go/logging"
 	"context"
 	"fmt"
-	"github.com/playwright-community/playwright-go"
 	"io"
 	"log"
+	"log/slog"
 	"os"
 	"path/filepath"
+	"strings"
+	"sync"
 	"time"
 )
 
-// logger is an interface for logging.
-type logger interface {
+// Levels of Logger.Summary and Logger.Fatal, next to the slog levels.
+const (
+	LevelSummary = slog.Level(2)
+	LevelFatal   = slog.Level(12)
+)
+
+// Log output formats, selected with LOG_FORMAT.
+const (
+	LogFormatPretty = "pretty"
+	LogFormatText   = "text"
+	LogFormatJSON   = "json"
+)
+
+// Logger is the logging interface of the crawler.
+// Replace it with Crawler.SetLogger, or send the records of the default logger to another sink with Crawler.AddLogHandler.
+type Logger interface {
+	Debug(format string, args ...interface{})
 	Info(format string, args ...interface{})
+	Summary(format string, args ...interface{})
+	Warn(format string, args ...interface{})
 	Error(format string, args ...interface{})
+	// Fatal logs the message and shuts the crawler down gracefully: no new urls are handed out and the urls in flight finish.
+	// Unlike a termination signal the run is failed, Stop does not mark it interrupted for the next run to resume.
 	Fatal(format string, args ...interface{})
 	Printf(format string, args ...interface{})
-	Summary(format string, args ...interface{})
-	Html(page playwright.Page, message string)
+	// Html dumps the page content of url under storage/logs/<site>/html, for inspecting failed pages.
+	Html(html, url, msg string, dir ...string)
+	// With returns a Logger adding structured fields (key value pairs, e.g. "url", url) to every record.
+	With(args ...interface{}) Logger
 }
 
-// defaultLogger is a default implementation of the logger interface using the standard log package.
+// defaultLogger is the default Logger, built on log/slog.
+// Records go to stdout (and the log file when ENABLE_FILE_LOGGING is set), to GCP logging on GCE and to the handlers added by AddLogHandler.
 type defaultLogger struct {
-	logger              *log.Logger
-	app                 *Crawler
-	gcpLogger           *logging.Logger // GCP Summary logger
-	gcpDebugLogger      *logging.Logger // GCP Debug logger
-	gcpMonitoringLogger *logging.Logger // GCP Debug logger
+	app     *Crawler
+	logger  *slog.Logger
+	handler *fanoutHandler
 }
 
 // newDefaultLogger creates a new instance of defaultLogger.
 func newDefaultLogger(app *Crawler, siteName string) *defaultLogger {
-	var multiWriter io.Writer = os.Stdout // By default, log only to stdout.
+	var writer io.Writer = os.Stdout // By default, log only to stdout.
 
 	// Check if file logging is enabled in the config.
 	if app.Config.GetBool("ENABLE_FILE_LOGGING") {
@@ -42,23 +64,22 @@ func newDefaultLogger(app *Crawler, siteName string) *defaultLogger {
 		if err != nil {
 			log.Fatalf("Failed to open log file: %v", err)
 		}
-		multiWriter = io.MultiWriter(logFile, os.Stdout)
+		writer = io.MultiWriter(logFile, os.Stdout)
 	}
 
-	// Create the default logger
-	dLogger := &defaultLogger{
-		logger: log.New(multiWriter, "【"+app.Name+"】", log.LstdFlags),
-		app:    app,
-	}
+	level := parseLogLevel(app.Config.GetString("LOG_LEVEL"))
+	handler := &fanoutHandler{handlers: []slog.Handler{newLogHandler(app.Config.GetString("LOG_FORMAT"), writer, app.Name, level)}}
 
 	// Initialize GCP logger if requested
-	if metadata.OnGCE() {
-		dLogger.gcpLogger = getGCPLogger(app.Config, "summary_log")
-		dLogger.gcpDebugLogger = getGCPLogger(app.Config, "dev_log")
-		dLogger.gcpMonitoringLogger = getGCPLogger(app.Config, "monitoring_log")
+	if metadata.OnGCE() && app.Config.GetBool("LOG_TO_GCP") {
+		handler.handlers = append(handler.handlers, newGCPHandler(app.Config, level))
 	}
 
-	return dLogger
+	return &defaultLogger{
+		app:     app,
+		logger:  slog.New(handler).With("site", app.Name),
+		handler: handler,
+	}
 }
 
 func getLogFileName(siteName string) string {
@@ -72,106 +93,357 @@ func getLogFileName(siteName string) string {
 	// Return the log file path with the formatted current date.
 	return filepath.Join(directory, fmt.Sprintf("%s_application.log", currentDate))
 }
-func getGCPLogger(config *configService, logID string) *logging.Logger {
-
-	os.Setenv("GCP_LOG_CREDENTIALS_PATH", "log-key.json")
-	projectID := config.EnvString("PROJECT_ID", "lazuli-venturas-stg")
 
-	client, err := logging.NewClient(context.Background(), projectID)
-	if err != nil {
-		panic(fmt.Sprintf("Failed to create GCP logging client: %v", err))
+// parseLogLevel parses LOG_LEVEL (debug, info, summary, warn, error), defaulting to debug.
+func parseLogLevel(level string) slog.Level {
+	switch strings.ToLower(level) {
+	case "info":
+		return slog.LevelInfo
+	case "summary":
+		return LevelSummary
+	case "warn", "warning":
+		return slog.LevelWarn
+	case "error":
+		return slog.LevelError
 	}
-
-	return client.Logger(logID)
+	return slog.LevelDebug
 }
 
-// logWithGCP logs both to the local logger
-func (l *defaultLogger) logWithGCP(level string, format string, args ...interface{}) {
-	ts := time.Now().Format("2006-01-02 15:04:05")
-	msg := fmt.Sprintf(format, args...)
+func levelName(level slog.Level) string {
+	switch level {
+	case LevelSummary:
+		return "SUMMARY"
+	case LevelFatal:
+		return "FATAL"
+	}
+	return level.String()
+}
 
-	// Log to local logger
-	l.logger.Printf("☁️ "+format, args...)
+// newLogHandler returns the handler of LOG_FORMAT writing to w.
+func newLogHandler(format string, w io.Writer, siteName string, level slog.Level) slog.Handler {
+	opts := &slog.HandlerOptions{
+		Level: level,
+		ReplaceAttr: func(groups []string, a slog.Attr) slog.Attr {
+			if a.Key == slog.LevelKey && len(groups) == 0 {
+				return slog.String(slog.LevelKey, levelName(a.Value.Any().(slog.Level)))
+			}
+			return a
+		},
+	}
+	switch format {
+	case LogFormatJSON:
+		return slog.NewJSONHandler(w, opts)
+	case LogFormatText:
+		return slog.NewTextHandler(w, opts)
+	}
+	return &prettyHandler{w: w, mu: &sync.Mutex{}, prefix: "【" + siteName + "】", level: level}
+}
 
-	if !l.app.Config.GetBool("LOG_TO_GCP") {
+func (l *defaultLogger) log(level slog.Level, format string, args ...interface{}) {
+	ctx := context.Background()
+	if !l.logger.Enabled(ctx, level) {
 		return
 	}
-	if l.gcpLogger != nil && level == "summary" {
-		l.gcpLogger.Log(logging.Entry{
-			Payload: map[string]interface{}{
-				"level":     "info",
-				"caller":    "ninjacrawler/logger.go",
-				"ts":        ts,
-				"msg":       msg,
-				"site_name": l.app.Name,
-			},
-			Severity: logging.Info,
-		})
-		// Flush GCP logger to ensure the log is sent immediately
-		defer l.gcpLogger.Flush()
-	}
-	if l.gcpDebugLogger != nil && level == "debug" {
-		l.gcpDebugLogger.Log(logging.Entry{
-			Payload: map[string]interface{}{
-				"level":     "error",
-				"caller":    "ninjacrawler/logger.go",
-				"ts":        ts,
-				"msg":       msg,
-				"site_name": l.app.Name,
-			},
-			Severity: logging.Debug,
-		})
-		// Flush GCP logger to ensure the log is sent immediately
-		defer l.gcpDebugLogger.Flush()
-	}
-	if l.gcpMonitoringLogger != nil && level == "monitoring" {
-		l.gcpMonitoringLogger.Log(logging.Entry{
-			Payload: map[string]interface{}{
-				"level":     "monitoring",
-				"caller":    "ninjacrawler/logger.go",
-				"ts":        ts,
-				"msg":       msg,
-				"site_name": l.app.Name,
-			},
-			Severity: logging.Info,
-		})
-		// Flush GCP logger to ensure the log is sent immediately
-		defer l.gcpMonitoringLogger.Flush()
-	}
+	l.logger.Log(ctx, level, fmt.Sprintf(format, args...))
 }
+
 func (l *defaultLogger) Summary(format string, args ...interface{}) {
-	l.logWithGCP("summary", format, args...)
+	l.log(LevelSummary, format, args...)
 }
 func (l *defaultLogger) Debug(format string, args ...interface{}) {
-	l.logWithGCP("debug", format, args...)
-}
-func (l *defaultLogger) monitoring(format string, args ...interface{}) {
-	l.logWithGCP("monitoring", format, args...)
+	l.log(slog.LevelDebug, format, args...)
 }
 func (l *defaultLogger) Info(format string, args ...interface{}) {
-	l.logger.Printf("✔ "+format, args...)
+	l.log(slog.LevelInfo, format, args...)
 }
 func (l *defaultLogger) Warn(format string, args ...interface{}) {
-	l.logger.Printf("⚠️ "+format, args...)
+	l.log(slog.LevelWarn, format, args...)
 }
 
 func (l *defaultLogger) Error(format string, args ...interface{}) {
-	l.logger.Printf("🛑 ERROR: "+format, args...)
+	l.log(slog.LevelError, format, args...)
 }
 
 func (l *defaultLogger) Fatal(format string, args ...interface{}) {
-	l.logger.Fatalf("🚨 FATAL: "+format, args...)
+	l.log(LevelFatal, format, args...)
+	l.handler.flush()
+	l.app.fail()
 }
 
 func (l *defaultLogger) Printf(format string, args ...interface{}) {
-	l.logger.Printf(format, args...)
+	l.log(slog.LevelInfo, format, args...)
 }
 
 func (l *defaultLogger) Html(html, url, msg string, dir ...string) {
 	if l.app.IsValidPage(url) {
 		err := l.app.writePageContentToFile(html, url, msg, dir...)
 		if err != nil {
-			l.logger.Printf("⚛️ HTML: %v", err)
+			l.Error("HTML: %v", err)
 		}
 	}
 }
+
+func (l *defaultLogger) With(args ...interface{}) Logger {
+	return &defaultLogger{
+		app:     l.app,
+		logger:  l.logger.With(args...),
+		handler: l.handler,
+	}
+}
+
+// SetLogger replaces the logger of the crawler.
+func (app *Crawler) SetLogger(logger Logger) *Crawler {
+	app.Logger = logger
+	return app
+}
+
+// AddLogHandler sends the records of the default logger to handler as well, e.g. to ship logs to another sink.
+func (app *Crawler) AddLogHandler(handler slog.Handler) *Crawler {
+	if l, ok := app.Logger.(*defaultLogger); ok {
+		l.handler.add(handler)
+	}
+	return app
+}
+
+// fanoutHandler passes every record to all of its handlers.
+type fanoutHandler struct {
+	mu       sync.RWMutex
+	handlers []slog.Handler
+	attrs    []slog.Attr
+	group    string
+}
+
+func (h *fanoutHandler) add(handler slog.Handler) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.handlers = append(h.handlers, handler)
+}
+
+func (h *fanoutHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	for _, handler := range h.handlers {
+		if handler.Enabled(ctx, level) {
+			return true
+		}
+	}
+	return false
+}
+
+func (h *fanoutHandler) Handle(ctx context.Context, record slog.Record) error {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	var errs []error
+	for _, handler := range h.handlers {
+		if !handler.Enabled(ctx, record.Level) {
+			continue
+		}
+		if err := handler.Handle(ctx, record.Clone()); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("log handlers failed: %v", errs)
+	}
+	return nil
+}
+
+// WithAttrs and WithGroup are applied lazily, so handlers added later by AddLogHandler see them as well.
+func (h *fanoutHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &derivedHandler{parent: h, attrs: attrs}
+}
+
+func (h *fanoutHandler) WithGroup(name string) slog.Handler {
+	return &derivedHandler{parent: h, group: name}
+}
+
+func (h *fanoutHandler) flush() {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	for _, handler := range h.handlers {
+		if f, ok := handler.(interface{ flush() }); ok {
+			f.flush()
+		}
+	}
+}
+
+// derivedHandler applies the attrs and group of Logger.With to the current handlers of a fanoutHandler.
+type derivedHandler struct {
+	parent *fanoutHandler
+	prev   *derivedHandler
+	attrs  []slog.Attr
+	group  string
+}
+
+func (h *derivedHandler) resolve(handler slog.Handler) slog.Handler {
+	if h.prev != nil {
+		handler = h.prev.resolve(handler)
+	}
+	if h.group != "" {
+		return handler.WithGroup(h.group)
+	}
+	return handler.WithAttrs(h.attrs)
+}
+
+func (h *derivedHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.parent.Enabled(ctx, level)
+}
+
+func (h *derivedHandler) Handle(ctx context.Context, record slog.Record) error {
+	h.parent.mu.RLock()
+	handlers := append([]slog.Handler(nil), h.parent.handlers...)
+	h.parent.mu.RUnlock()
+	for _, handler := range handlers {
+		if handler.Enabled(ctx, record.Level) {
+			_ = h.resolve(handler).Handle(ctx, record.Clone())
+		}
+	}
+	return nil
+}
+
+func (h *derivedHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &derivedHandler{parent: h.parent, prev: h, attrs: attrs}
+}
+
+func (h *derivedHandler) WithGroup(name string) slog.Handler {
+	return &derivedHandler{parent: h.parent, prev: h, group: name}
+}
+
+// prettyHandler writes the human readable format of the crawler: 【site】2006/01/02 15:04:05 ✔ message key=value
+type prettyHandler struct {
+	w      io.Writer
+	mu     *sync.Mutex
+	prefix string
+	level  slog.Level
+	attrs  []slog.Attr
+}
+
+var prettyLevelPrefixes = map[slog.Level]string{
+	slog.LevelDebug: "☁️ ",
+	slog.LevelInfo:  "✔ ",
+	LevelSummary:    "☁️ ",
+	slog.LevelWarn:  "⚠️ ",
+	slog.LevelError: "🛑 ERROR: ",
+	LevelFatal:      "🚨 FATAL: ",
+}
+
+func (h *prettyHandler) Enabled(_ context.Context, level slog.Level) bool {
+	return level >= h.level
+}
+
+func (h *prettyHandler) Handle(_ context.Context, record slog.Record) error {
+	var b strings.Builder
+	b.WriteString(h.prefix)
+	b.WriteString(record.Time.Format("2006/01/02 15:04:05 "))
+	b.WriteString(prettyLevelPrefixes[record.Level])
+	b.WriteString(record.Message)
+	writeAttr := func(a slog.Attr) bool {
+		if a.Key != "site" { // Already in the prefix
+			fmt.Fprintf(&b, " %s=%v", a.Key, a.Value)
+		}
+		return true
+	}
+	for _, a := range h.attrs {
+		writeAttr(a)
+	}
+	record.Attrs(writeAttr)
+	b.WriteString("\n")
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	_, err := io.WriteString(h.w, b.String())
+	return err
+}
+
+func (h *prettyHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	clone := *h
+	clone.attrs = append(append([]slog.Attr(nil), h.attrs...), attrs...)
+	return &clone
+}
+
+func (h *prettyHandler) WithGroup(string) slog.Handler {
+	return h
+}
+
+// gcpHandler sends summaries to the summary_log of GCP logging, the monitoring report to monitoring_log and debug records to dev_log.
+type gcpHandler struct {
+	level            slog.Level
+	attrs            []slog.Attr
+	summaryLogger    *logging.Logger
+	debugLogger      *logging.Logger
+	monitoringLogger *logging.Logger
+}
+
+func newGCPHandler(config *configService, level slog.Level) *gcpHandler {
+	return &gcpHandler{
+		level:            level,
+		summaryLogger:    getGCPLogger(config, "summary_log"),
+		debugLogger:      getGCPLogger(config, "dev_log"),
+		monitoringLogger: getGCPLogger(config, "monitoring_log"),
+	}
+}
+
+func getGCPLogger(config *configService, logID string) *logging.Logger {
+
+	os.Setenv("GCP_LOG_CREDENTIALS_PATH", "log-key.json")
+	projectID := config.EnvString("PROJECT_ID", "lazuli-venturas-stg")
+
+	client, err := logging.NewClient(context.Background(), projectID)
+	if err != nil {
+		panic(fmt.Sprintf("Failed to create GCP logging client: %v", err))
+	}
+
+	return client.Logger(logID)
+}
+
+func (h *gcpHandler) Enabled(_ context.Context, level slog.Level) bool {
+	return level >= h.level && (level == slog.LevelDebug || level == LevelSummary)
+}
+
+func (h *gcpHandler) Handle(_ context.Context, record slog.Record) error {
+	payload := map[string]interface{}{
+		"level":  strings.ToLower(levelName(record.Level)),
+		"caller": "ninjacrawler/logger.go",
+		"ts":     record.Time.Format("2006-01-02 15:04:05"),
+		"msg":    record.Message,
+	}
+	addAttr := func(a slog.Attr) bool {
+		payload[a.Key] = a.Value.Any()
+		return true
+	}
+	for _, a := range h.attrs {
+		addAttr(a)
+	}
+	record.Attrs(addAttr)
+	if site, ok := payload["site"]; ok {
+		payload["site_name"] = site
+		delete(payload, "site")
+	}
+
+	gcpLogger, severity := h.debugLogger, logging.Debug
+	switch {
+	case payload["report"] == "monitoring":
+		gcpLogger, severity = h.monitoringLogger, logging.Info
+	case record.Level == LevelSummary:
+		gcpLogger, severity = h.summaryLogger, logging.Info
+	}
+	gcpLogger.Log(logging.Entry{Payload: payload, Severity: severity})
+	// Flush GCP logger to ensure the log is sent immediately
+	return gcpLogger.Flush()
+}
+
+func (h *gcpHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	clone := *h
+	clone.attrs = append(append([]slog.Attr(nil), h.attrs...), attrs...)
+	return &clone
+}
+
+func (h *gcpHandler) WithGroup(string) slog.Handler {
+	return h
+}
+
+func (h *gcpHandler) flush() {
+	_ = h.summaryLogger.Flush()
+	_ = h.debugLogger.Flush()
+	_ = h.monitoringLogger.Flush()
+}
@@ -0,0 +1,38 @@
+package ninjacrawler
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// TestMain runs the tests in a temporary directory, so the storage directory of the crawlers is not written into the repo.
+func TestMain(m *testing.M) {
+	dir, err := os.MkdirTemp("", "ninjacrawler")
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	if err := os.Chdir(dir); err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	code := m.Run()
+	_ = os.RemoveAll(dir)
+	os.Exit(code)
+}
+
+// newTestCrawler returns a crawler of site backed by a BoltDB in the temp directory of t.
+func newTestCrawler(t *testing.T, site, url string, engines ...Engine) *Crawler {
+	t.Helper()
+	t.Setenv("DB_DRIVER", StoreDriverBolt)
+	t.Setenv("DB_PATH", filepath.Join(t.TempDir(), site+".db"))
+	t.Setenv("APP_ENV", "local")
+	t.Setenv("SKIP_PROXY_CHECK", "true")
+	app := NewCrawler(site, url, engines...)
+	t.Cleanup(func() {
+		_ = app.store.Close()
+	})
+	return app
+}
@@ -0,0 +1,199 @@
+package ninjacrawler
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// crawlerMetrics holds the Prometheus metrics of a crawler, served in text format at /metrics of the monitoring panel.
+type crawlerMetrics struct {
+	registry           *prometheus.Registry
+	requests           *prometheus.CounterVec
+	requestSuccesses   *prometheus.CounterVec
+	requestFailures    *prometheus.CounterVec
+	navigationDuration *prometheus.HistogramVec
+	extractionDuration *prometheus.HistogramVec
+	dbWriteDuration    *prometheus.HistogramVec
+	openBrowsers       prometheus.Gauge
+}
+
+func newCrawlerMetrics(app *Crawler) *crawlerMetrics {
+	requestLabels := []string{"collection", "provider", "proxy"}
+	statusLabels := append(requestLabels, "status_code")
+	constLabels := prometheus.Labels{"site": app.Name}
+
+	m := &crawlerMetrics{
+		registry: prometheus.NewRegistry(),
+		requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name:        "ninjacrawler_requests_total",
+			Help:        "Number of navigations started.",
+			ConstLabels: constLabels,
+		}, requestLabels),
+		requestSuccesses: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name:        "ninjacrawler_request_successes_total",
+			Help:        "Number of navigations which returned a page.",
+			ConstLabels: constLabels,
+		}, statusLabels),
+		requestFailures: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name:        "ninjacrawler_request_failures_total",
+			Help:        "Number of navigations which failed, status_code is empty when no response was received.",
+			ConstLabels: constLabels,
+		}, statusLabels),
+		navigationDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:        "ninjacrawler_navigation_duration_seconds",
+			Help:        "Duration of navigations, including the wait for the rate limiter.",
+			ConstLabels: constLabels,
+			Buckets:     []float64{0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60, 120},
+		}, []string{"collection", "provider"}),
+		extractionDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:        "ninjacrawler_extraction_duration_seconds",
+			Help:        "Duration of running the processor on a navigated page.",
+			ConstLabels: constLabels,
+			Buckets:     prometheus.DefBuckets,
+		}, []string{"collection"}),
+		dbWriteDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:        "ninjacrawler_db_write_duration_seconds",
+			Help:        "Duration of database writes.",
+			ConstLabels: constLabels,
+			Buckets:     prometheus.DefBuckets,
+		}, []string{"operation"}),
+		openBrowsers: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name:        "ninjacrawler_open_browsers",
+			Help:        "Number of browsers currently open.",
+			ConstLabels: constLabels,
+		}),
+	}
+	queueDepth := prometheus.NewDesc("ninjacrawler_queue_depth",
+		"Number of pending urls in the origin collection of the current processor.",
+		[]string{"collection"}, constLabels)
+
+	m.registry.MustRegister(
+		m.requests,
+		m.requestSuccesses,
+		m.requestFailures,
+		m.navigationDuration,
+		m.extractionDuration,
+		m.dbWriteDuration,
+		m.openBrowsers,
+		&queueDepthCollector{app: app, desc: queueDepth},
+	)
+	return m
+}
+
+// queueDepthCollector counts the pending urls of the current processor when metrics are scraped.
+type queueDepthCollector struct {
+	app  *Crawler
+	desc *prometheus.Desc
+}
+
+func (c *queueDepthCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.desc
+}
+
+func (c *queueDepthCollector) Collect(ch chan<- prometheus.Metric) {
+	config := c.app.currentProcessor()
+	collection := config.OriginCollection
+	if collection == "" || c.app.store == nil {
+		return
+	}
+	// Same filter as getUrlCollections, the urls the processor still has to crawl
+	pending, err := c.app.store.CountUrlCollections(context.Background(), collection, UrlFilter{
+		Status:      Bool(false),
+		MaxAttempts: Int(c.app.resolveEngine(&config.Engine).MaxRetryAttempts),
+	})
+	if err != nil {
+		return
+	}
+	ch <- prometheus.MustNewConstMetric(c.desc, prometheus.GaugeValue, float64(pending), collection)
+}
+
+// metricsHandler serves the metrics of the crawler in Prometheus text format.
+func (app *Crawler) metricsHandler() http.Handler {
+	return promhttp.HandlerFor(app.metrics.registry, promhttp.HandlerOpts{})
+}
+
+// fetchProvider names the way a page is fetched with engine, used as the provider label.
+func fetchProvider(engine *Engine, navigateToApi bool) string {
+	if navigateToApi && !*engine.IsDynamic {
+		return "api"
+	}
+	return providerName(engine)
+}
+
+// observeNavigation records the outcome and duration of a navigation, in the metrics and the health of the proxy.
+func (app *Crawler) observeNavigation(collection string, provider string, proxy Proxy, start time.Time, err error) {
+	duration := time.Since(start)
+	app.proxyPool.record(proxy, duration, err)
+	app.metrics.requests.WithLabelValues(collection, provider, proxy.Server).Inc()
+	app.metrics.navigationDuration.WithLabelValues(collection, provider).Observe(duration.Seconds())
+
+	statusCode := ""
+	var statusErr *HTTPStatusError
+	var notModifiedErr *NotModifiedError
+	switch {
+	case err == nil:
+		statusCode = strconv.Itoa(http.StatusOK)
+	case errors.As(err, &notModifiedErr):
+		statusCode = strconv.Itoa(http.StatusNotModified)
+	case errors.As(err, &statusErr):
+		statusCode = strconv.Itoa(statusErr.StatusCode)
+	}
+	if err == nil || notModifiedErr != nil {
+		app.metrics.requestSuccesses.WithLabelValues(collection, provider, proxy.Server, statusCode).Inc()
+		return
+	}
+	app.metrics.requestFailures.WithLabelValues(collection, provider, proxy.Server, statusCode).Inc()
+}
+
+// observeExtraction records the duration of running the processor of collection.
+func (app *Crawler) observeExtraction(collection string, start time.Time) {
+	app.metrics.extractionDuration.WithLabelValues(collection).Observe(time.Since(start).Seconds())
+}
+
+// metricsStore records the duration of every write to the wrapped Store.
+type metricsStore struct {
+	Store
+	metrics *crawlerMetrics
+}
+
+func (app *Crawler) instrumentStore(store Store) Store {
+	if _, ok := store.(*metricsStore); ok || store == nil {
+		return store
+	}
+	return &metricsStore{Store: store, metrics: app.metrics}
+}
+
+func (s *metricsStore) observe(operation string, start time.Time) {
+	s.metrics.dbWriteDuration.WithLabelValues(operation).Observe(time.Since(start).Seconds())
+}
+
+func (s *metricsStore) InsertUrlCollections(ctx context.Context, collection string, urlCollections []UrlCollection) error {
+	defer s.observe("insert_url_collections", time.Now())
+	return s.Store.InsertUrlCollections(ctx, collection, urlCollections)
+}
+
+func (s *metricsStore) UpdateUrlCollection(ctx context.Context, collection string, url string, fields Map) error {
+	defer s.observe("update_url_collection", time.Now())
+	return s.Store.UpdateUrlCollection(ctx, collection, url, fields)
+}
+
+func (s *metricsStore) SaveProductDetail(ctx context.Context, collection string, productDetail *ProductDetail) error {
+	defer s.observe("save_product_detail", time.Now())
+	return s.Store.SaveProductDetail(ctx, collection, productDetail)
+}
+
+func (s *metricsStore) InsertSite(ctx context.Context, site SiteCollection) error {
+	defer s.observe("insert_site", time.Now())
+	return s.Store.InsertSite(ctx, site)
+}
+
+func (s *metricsStore) UpdateSite(ctx context.Context, url string, fields Map) error {
+	defer s.observe("update_site", time.Now())
+	return s.Store.UpdateSite(ctx, url, fields)
+}
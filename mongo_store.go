@@ -0,0 +1,272 @@
+package ninjacrawler
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// mongoStore is the MongoDB implementation of Store. Every site gets its own database.
+type mongoStore struct {
+	app    *Crawler
+	client *mongo.Client
+}
+
+func newMongoStore(app *Crawler) *mongoStore {
+	return &mongoStore{
+		app:    app,
+		client: app.mustGetClient(),
+	}
+}
+
+func (app *Crawler) mustGetClient() *mongo.Client {
+	ctx, cancel := context.WithTimeout(context.Background(), dbTimeout)
+	defer cancel()
+	databaseURL := fmt.Sprintf("mongodb://%s:%s@%s:%s",
+		app.Config.Env("DB_USERNAME"),
+		app.Config.Env("DB_PASSWORD"),
+		app.Config.Env("DB_HOST"),
+		app.Config.Env("DB_PORT"),
+	)
+	clientOptions := options.Client().
+		ApplyURI(databaseURL).
+		SetMaxPoolSize(uint64(app.currentEngine().ConcurrentLimit * 2)).
+		SetServerSelectionTimeout(10 * time.Second)
+
+	client, err := mongo.Connect(ctx, clientOptions)
+	if err != nil {
+		panic(err)
+	}
+
+	// Check if the connection is established
+	err = client.Ping(ctx, nil)
+	if err != nil {
+		app.Logger.Error("Failed to ping MongoDB: %v", err)
+		panic(err)
+	}
+
+	return client
+}
+
+// getCollection returns a collection from the database and ensures unique indexing.
+func (s *mongoStore) getCollection(ctx context.Context, collectionName string) *mongo.Collection {
+	collection := s.client.Database(s.app.Name).Collection(collectionName)
+	if !contains(s.app.preference.ExcludeUniqueUrlEntities, collectionName) {
+		s.ensureUniqueIndex(ctx, collection, s.app.collectionIndex(collectionName))
+	}
+	return collection
+}
+
+// ensureUniqueIndex ensures that the "url" field in the collection has a unique index.
+func (s *mongoStore) ensureUniqueIndex(ctx context.Context, collection *mongo.Collection, index []string) {
+	// Create a slice for the index keys, starting with the default 'url'
+	indexKeys := bson.D{{Key: "url", Value: 1}}
+
+	// Add additional unique fields, avoiding duplicates
+	for _, field := range index {
+		indexKeys = append(indexKeys, bson.E{Key: field, Value: 1})
+	}
+
+	// Create the index model
+	indexModel := mongo.IndexModel{
+		Keys:    indexKeys, // Use bson.D for ordered field key-value pairs
+		Options: options.Index().SetUnique(true),
+	}
+
+	// Create the index on the collection
+	_, err := collection.Indexes().CreateOne(ctx, indexModel)
+	if err != nil {
+		s.app.Logger.Error("Could not create index: %v", err)
+	}
+}
+
+func (s *mongoStore) InsertUrlCollections(ctx context.Context, collection string, urlCollections []UrlCollection) error {
+	if len(urlCollections) == 0 {
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(ctx, dbTimeout)
+	defer cancel()
+
+	var documents []interface{}
+	for _, urlCollection := range urlCollections {
+		documents = append(documents, urlCollection)
+	}
+
+	opts := options.InsertMany().SetOrdered(false)
+	_, err := s.getCollection(ctx, collection).InsertMany(ctx, documents, opts)
+	if err != nil && !mongo.IsDuplicateKeyError(err) {
+		return err
+	}
+	return nil
+}
+
+func (s *mongoStore) FindUrlCollections(ctx context.Context, collection string, filter UrlFilter) ([]UrlCollection, error) {
+	findOptions := options.Find()
+	if filter.Limit > 0 {
+		findOptions.SetLimit(int64(filter.Limit))
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, dbTimeout)
+	defer cancel()
+
+	cursor, err := s.getCollection(ctx, collection).Find(ctx, mongoUrlFilter(filter), findOptions)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var results []UrlCollection
+	if err := cursor.All(ctx, &results); err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+func (s *mongoStore) FindUrlCollection(ctx context.Context, collection string, url string) (*UrlCollection, error) {
+	ctx, cancel := context.WithTimeout(ctx, dbTimeout)
+	defer cancel()
+
+	var result UrlCollection
+	err := s.getCollection(ctx, collection).FindOne(ctx, bson.D{{Key: "url", Value: url}}).Decode(&result)
+	if err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+func (s *mongoStore) UpdateUrlCollection(ctx context.Context, collection string, url string, fields Map) error {
+	ctx, cancel := context.WithTimeout(ctx, dbTimeout)
+	defer cancel()
+
+	set := bson.D{}
+	for key, value := range fields {
+		set = append(set, bson.E{Key: key, Value: value})
+	}
+	filter := bson.D{{Key: "url", Value: url}}
+	update := bson.D{{Key: "$set", Value: set}}
+
+	_, err := s.getCollection(ctx, collection).UpdateOne(ctx, filter, update)
+	return err
+}
+
+func (s *mongoStore) CountUrlCollections(ctx context.Context, collection string, filter UrlFilter) (int, error) {
+	ctx, cancel := context.WithTimeout(ctx, dbTimeout)
+	defer cancel()
+
+	count, err := s.getCollection(ctx, collection).CountDocuments(ctx, mongoUrlFilter(filter))
+	if err != nil {
+		return 0, err
+	}
+	return int(count), nil
+}
+
+func (s *mongoStore) SaveProductDetail(ctx context.Context, collection string, productDetail *ProductDetail) error {
+	ctx, cancel := context.WithTimeout(ctx, dbTimeout)
+	defer cancel()
+
+	mongoCollection := s.getCollection(ctx, collection)
+	if contains(s.app.preference.ExcludeUniqueUrlEntities, collection) {
+		_, err := mongoCollection.InsertOne(ctx, productDetail)
+		return err
+	}
+	_, err := mongoCollection.ReplaceOne(ctx, bson.D{{Key: "url", Value: productDetail.Url}}, productDetail, options.Replace().SetUpsert(true))
+	return err
+}
+
+func (s *mongoStore) FindProductDetail(ctx context.Context, collection string, url string) (*ProductDetail, error) {
+	ctx, cancel := context.WithTimeout(ctx, dbTimeout)
+	defer cancel()
+
+	var result ProductDetail
+	err := s.getCollection(ctx, collection).FindOne(ctx, bson.D{{Key: "url", Value: url}}).Decode(&result)
+	if err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+func (s *mongoStore) FindProductDetails(ctx context.Context, collection string, skip, limit int) ([]ProductDetail, error) {
+	findOptions := options.Find().
+		SetSkip(int64(skip)).
+		SetLimit(int64(limit))
+
+	ctx, cancel := context.WithTimeout(ctx, dbTimeout)
+	defer cancel()
+
+	cursor, err := s.getCollection(ctx, collection).Find(ctx, bson.D{}, findOptions)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var results []ProductDetail
+	if err := cursor.All(ctx, &results); err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+func (s *mongoStore) InsertSite(ctx context.Context, site SiteCollection) error {
+	ctx, cancel := context.WithTimeout(ctx, dbTimeout)
+	defer cancel()
+
+	_, err := s.getCollection(ctx, baseCollection).InsertOne(ctx, site)
+	if err != nil && !mongo.IsDuplicateKeyError(err) {
+		return err
+	}
+	return nil
+}
+
+func (s *mongoStore) FindSite(ctx context.Context, url string) (*SiteCollection, error) {
+	ctx, cancel := context.WithTimeout(ctx, dbTimeout)
+	defer cancel()
+
+	var result SiteCollection
+	err := s.getCollection(ctx, baseCollection).FindOne(ctx, bson.D{{Key: "url", Value: url}}).Decode(&result)
+	if err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+func (s *mongoStore) UpdateSite(ctx context.Context, url string, fields Map) error {
+	return s.UpdateUrlCollection(ctx, baseCollection, url, fields)
+}
+
+func (s *mongoStore) Drop(ctx context.Context) error {
+	ctx, cancel := context.WithTimeout(ctx, dbTimeout)
+	defer cancel()
+	return s.client.Database(s.app.Name).Drop(ctx)
+}
+
+func (s *mongoStore) Close() error {
+	ctx, cancel := context.WithTimeout(context.Background(), dbTimeout)
+	defer cancel()
+	return s.client.Disconnect(ctx)
+}
+
+// mongoUrlFilter converts a UrlFilter into a bson filter.
+func mongoUrlFilter(filter UrlFilter) bson.D {
+	condition := bson.D{}
+	if filter.Status != nil {
+		condition = append(condition, bson.E{Key: "status", Value: *filter.Status})
+	}
+	if filter.Error != nil {
+		condition = append(condition, bson.E{Key: "error", Value: *filter.Error})
+	}
+	attempts := bson.D{}
+	if filter.MinAttempts != nil {
+		attempts = append(attempts, bson.E{Key: "$gte", Value: *filter.MinAttempts})
+	}
+	if filter.MaxAttempts != nil {
+		attempts = append(attempts, bson.E{Key: "$lt", Value: *filter.MaxAttempts})
+	}
+	if len(attempts) > 0 {
+		condition = append(condition, bson.E{Key: "attempts", Value: attempts})
+	}
+	return condition
+}
@@ -0,0 +1,339 @@
+package ninjacrawler
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	monitorSampleLimit = 20
+	monitorErrorLimit  = 50
+)
+
+// monitorPanel serves the dashboard and JSON API of a running crawler at MONITOR_ADDR.
+type monitorPanel struct {
+	app        *Crawler
+	server     *http.Server
+	mu         sync.Mutex
+	processors []ProcessorConfig
+	samples    []monitorSample
+}
+
+type monitorSample struct {
+	Entity        string        `json:"entity"`
+	ProductDetail ProductDetail `json:"product_detail"`
+	SavedAt       time.Time     `json:"saved_at"`
+}
+
+type monitorCollection struct {
+	Name     string `json:"name"`
+	Entity   bool   `json:"entity"` // Product detail collection, only Total is counted
+	Total    int    `json:"total"`
+	Pending  int    `json:"pending"`
+	Complete int    `json:"complete"`
+	Errors   int    `json:"errors"`
+}
+
+type monitorRequests struct {
+	Total         int32 `json:"total"`
+	Failed        int32 `json:"failed"`
+	PerMinute     int32 `json:"per_minute"`
+	PerFiveMinute int32 `json:"per_five_minute"`
+	PerHour       int32 `json:"per_hour"`
+	PerDay        int32 `json:"per_day"`
+}
+
+type monitorStatus struct {
+	SiteID      string              `json:"site_id"`
+	Url         string              `json:"url"`
+	StartedAt   time.Time           `json:"started_at"`
+	ElapsedTime int64               `json:"elapsed_time"`
+	Processor   string              `json:"processor"`
+	Requests    monitorRequests     `json:"requests"`
+	Collections []monitorCollection `json:"collections"`
+}
+
+type monitorError struct {
+	Collection string     `json:"collection"`
+	Url        string     `json:"url"`
+	ErrorLog   string     `json:"error_log"`
+	Attempts   int        `json:"attempts"`
+	UpdatedAt  *time.Time `json:"updated_at"`
+	HtmlUrl    string     `json:"html_url,omitempty"`
+}
+
+// startMonitorPanel starts the monitoring panel when MONITOR_ADDR is set, e.g. MONITOR_ADDR=:8090.
+func (app *Crawler) startMonitorPanel() {
+	addr := app.Config.GetString("MONITOR_ADDR")
+	if addr == "" {
+		return
+	}
+	panel := &monitorPanel{app: app}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", panel.handleDashboard)
+	mux.HandleFunc("/api/status", panel.handleStatus)
+	mux.HandleFunc("/api/errors", panel.handleErrors)
+	mux.HandleFunc("/api/samples", panel.handleSamples)
+	mux.Handle("/metrics", app.metricsHandler())
+	mux.Handle("/html/", http.StripPrefix("/html/", http.FileServer(http.Dir(panel.htmlDir()))))
+	panel.server = &http.Server{Addr: addr, Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+	app.monitor = panel
+
+	go func() {
+		if err := panel.server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			app.Logger.Error("Monitoring panel stopped: %v", err)
+		}
+	}()
+	app.Logger.Summary("Monitoring panel listening on %s", addr)
+}
+
+// stopMonitorPanel stops serving the panel. The panel itself is kept, workers still running after
+// the shutdown grace period keep tracking into it.
+func (app *Crawler) stopMonitorPanel() {
+	if app.monitor == nil {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := app.monitor.server.Shutdown(ctx); err != nil {
+		app.Logger.Error("Failed to stop monitoring panel: %v", err)
+	}
+}
+
+// trackProcessor registers the collections of a processor on the monitoring panel.
+func (app *Crawler) trackProcessor(config ProcessorConfig) {
+	if app.monitor == nil {
+		return
+	}
+	app.monitor.mu.Lock()
+	defer app.monitor.mu.Unlock()
+	app.monitor.processors = append(app.monitor.processors, config)
+}
+
+// trackSample keeps the latest saved product details for the monitoring panel.
+func (app *Crawler) trackSample(entity string, productDetail *ProductDetail) {
+	if app.monitor == nil {
+		return
+	}
+	app.monitor.mu.Lock()
+	defer app.monitor.mu.Unlock()
+	app.monitor.samples = append(app.monitor.samples, monitorSample{Entity: entity, ProductDetail: *productDetail, SavedAt: time.Now()})
+	if len(app.monitor.samples) > monitorSampleLimit {
+		app.monitor.samples = app.monitor.samples[len(app.monitor.samples)-monitorSampleLimit:]
+	}
+}
+
+func (p *monitorPanel) htmlDir() string {
+	return filepath.Join("storage", "logs", p.app.Name, "html")
+}
+
+// collections returns the url collections and entities of the processors run so far, in crawl order.
+func (p *monitorPanel) collections() []monitorCollection {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	var collections []monitorCollection
+	seen := make(map[string]bool)
+	add := func(name string, entity bool) {
+		if name != "" && !seen[name] {
+			seen[name] = true
+			collections = append(collections, monitorCollection{Name: name, Entity: entity})
+		}
+	}
+	for _, processor := range p.processors {
+		add(processor.OriginCollection, false)
+		add(processor.Entity, isProductProcessor(processor))
+	}
+	return collections
+}
+
+// countCollections fills the counts of every collection.
+func (p *monitorPanel) countCollections(ctx context.Context, collections []monitorCollection) []monitorCollection {
+	for i := range collections {
+		collection := &collections[i]
+		collection.Total, _ = p.app.store.CountUrlCollections(ctx, collection.Name, UrlFilter{})
+		if !collection.Entity {
+			collection.Complete, _ = p.app.store.CountUrlCollections(ctx, collection.Name, UrlFilter{Status: Bool(true)})
+			collection.Errors, _ = p.app.store.CountUrlCollections(ctx, collection.Name, UrlFilter{Status: Bool(false), Error: Bool(true)})
+			collection.Pending, _ = p.app.store.CountUrlCollections(ctx, collection.Name, UrlFilter{Status: Bool(false), Error: Bool(false)})
+		}
+	}
+	return collections
+}
+
+// htmlFiles maps the url hash of every dumped page to its path relative to the html directory.
+func (p *monitorPanel) htmlFiles() map[string]string {
+	files := make(map[string]string)
+	root := p.htmlDir()
+	_ = filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() || !strings.HasSuffix(path, ".html") {
+			return nil
+		}
+		name := strings.TrimSuffix(info.Name(), ".html")
+		if i := strings.LastIndex(name, "_"); i >= 0 {
+			rel, _ := filepath.Rel(root, path)
+			files[name[i+1:]] = filepath.ToSlash(rel) // Walk is lexical and names start with the date, so the latest dump wins
+		}
+		return nil
+	})
+	return files
+}
+
+func (p *monitorPanel) handleStatus(w http.ResponseWriter, r *http.Request) {
+	metrics := &p.app.requestMetrics
+	writeMonitorJSON(w, monitorStatus{
+		SiteID:      p.app.Name,
+		Url:         p.app.Url,
+		StartedAt:   p.app.StartTime,
+		ElapsedTime: int64(time.Since(p.app.StartTime).Seconds()),
+		Processor:   p.app.currentProcessor().Entity,
+		Requests: monitorRequests{
+			Total:         atomic.LoadInt32(&metrics.ReqCount),
+			Failed:        atomic.LoadInt32(&metrics.FailedCount),
+			PerMinute:     atomic.LoadInt32(&metrics.MinuteReqCount),
+			PerFiveMinute: atomic.LoadInt32(&metrics.FiveMinuteReqCount),
+			PerHour:       atomic.LoadInt32(&metrics.HourlyReqCount),
+			PerDay:        atomic.LoadInt32(&metrics.DayReqCount),
+		},
+		Collections: p.countCollections(r.Context(), p.collections()),
+	})
+}
+
+func (p *monitorPanel) handleErrors(w http.ResponseWriter, r *http.Request) {
+	files := p.htmlFiles()
+	var errs []monitorError
+	for _, collection := range p.collections() {
+		if collection.Entity {
+			continue
+		}
+		for _, urlCollection := range p.app.GetErrorData(collection.Name) {
+			monitorErr := monitorError{
+				Collection: collection.Name,
+				Url:        urlCollection.Url,
+				ErrorLog:   urlCollection.ErrorLog,
+				Attempts:   urlCollection.Attempts,
+				UpdatedAt:  urlCollection.UpdatedAt,
+			}
+			if path, ok := files[urlHash(urlCollection.Url)]; ok {
+				monitorErr.HtmlUrl = "/html/" + path
+			}
+			errs = append(errs, monitorErr)
+		}
+	}
+	sort.SliceStable(errs, func(i, j int) bool {
+		if errs[i].UpdatedAt == nil || errs[j].UpdatedAt == nil {
+			return errs[j].UpdatedAt == nil && errs[i].UpdatedAt != nil
+		}
+		return errs[i].UpdatedAt.After(*errs[j].UpdatedAt)
+	})
+	if len(errs) > monitorErrorLimit {
+		errs = errs[:monitorErrorLimit]
+	}
+	writeMonitorJSON(w, errs)
+}
+
+func (p *monitorPanel) handleSamples(w http.ResponseWriter, r *http.Request) {
+	p.mu.Lock()
+	samples := make([]monitorSample, 0, len(p.samples))
+	for i := len(p.samples) - 1; i >= 0; i-- {
+		samples = append(samples, p.samples[i])
+	}
+	p.mu.Unlock()
+	writeMonitorJSON(w, samples)
+}
+
+func (p *monitorPanel) handleDashboard(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/" {
+		http.NotFound(w, r)
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	fmt.Fprint(w, monitorDashboard)
+}
+
+func writeMonitorJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+// urlHash returns the hash generateFilename appends to the dumped html of url.
+func urlHash(url string) string {
+	name := strings.TrimSuffix(generateFilename(url), ".html")
+	return name[strings.LastIndex(name, "_")+1:]
+}
+
+const monitorDashboard = `<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>Ninja Crawler</title>
+<style>
+body { font-family: sans-serif; margin: 2em; color: #222; }
+table { border-collapse: collapse; margin-bottom: 2em; width: 100%; }
+th, td { border: 1px solid #ddd; padding: 4px 8px; text-align: left; font-size: 13px; vertical-align: top; }
+th { background: #f4f4f4; }
+pre { margin: 0; white-space: pre-wrap; max-height: 12em; overflow: auto; }
+.error { color: #b00; }
+</style>
+</head>
+<body>
+<h1 id="site">Ninja Crawler</h1>
+<p id="summary"></p>
+<h2>Requests</h2>
+<table id="requests"></table>
+<h2>Collections</h2>
+<table id="collections"></table>
+<h2>Recent Errors</h2>
+<table id="errors"></table>
+<h2>Latest Samples</h2>
+<table id="samples"></table>
+<script>
+function esc(s) {
+  return String(s === undefined || s === null ? "" : s).replace(/[&<>"]/g, function (c) {
+    return {"&": "&amp;", "<": "&lt;", ">": "&gt;", '"': "&quot;"}[c];
+  });
+}
+function table(id, head, rows) {
+  document.getElementById(id).innerHTML = "<tr>" + head.map(function (h) { return "<th>" + h + "</th>"; }).join("") + "</tr>" +
+    rows.map(function (r) { return "<tr>" + r.map(function (c) { return "<td>" + c + "</td>"; }).join("") + "</tr>"; }).join("");
+}
+function refresh() {
+  fetch("api/status").then(function (r) { return r.json(); }).then(function (s) {
+    document.getElementById("site").textContent = s.site_id;
+    document.getElementById("summary").textContent = s.url + " | running " + s.elapsed_time + "s | processor: " + s.processor;
+    var q = s.requests;
+    table("requests", ["Total", "Failed", "Last minute", "Last 5 minutes", "Last hour", "Last day"],
+      [[q.total, q.failed, q.per_minute, q.per_five_minute, q.per_hour, q.per_day]]);
+    table("collections", ["Collection", "Total", "Pending", "Complete", "Errors"], (s.collections || []).map(function (c) {
+      return c.entity ? [esc(c.name), c.total, "", "", ""] : [esc(c.name), c.total, c.pending, c.complete, '<span class="error">' + c.errors + "</span>"];
+    }));
+  });
+  fetch("api/errors").then(function (r) { return r.json(); }).then(function (errs) {
+    table("errors", ["Collection", "Url", "Attempts", "Error", "Html"], (errs || []).map(function (e) {
+      return [esc(e.collection), esc(e.url), e.attempts, "<pre>" + esc(e.error_log) + "</pre>",
+        e.html_url ? '<a href="' + esc(e.html_url.substring(1)) + '" target="_blank">view</a>' : ""];
+    }));
+  });
+  fetch("api/samples").then(function (r) { return r.json(); }).then(function (samples) {
+    table("samples", ["Entity", "Saved", "Product"], (samples || []).map(function (s) {
+      return [esc(s.entity), esc(s.saved_at), "<pre>" + esc(JSON.stringify(s.product_detail, null, 2)) + "</pre>"];
+    }));
+  });
+}
+refresh();
+setInterval(refresh, 5000);
+</script>
+</body>
+</html>
+`
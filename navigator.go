@@ -2,62 +2,74 @@ package ninjacrawler
 
 import (
 	"context"
-	"strings"
 	"sync/atomic"
 	"time"
 )
 
 func (app *Crawler) Navigate(url string, engines ...Engine) (*NavigationContext, error) {
-	app.overrideEngineDefaults(app.engine, &app.CurrentProcessorConfig.Engine)
+	return app.NavigateContext(context.Background(), url, engines...)
+}
+
+// NavigateContext is Navigate with a context, aborting the navigation and the retries once ctx is done.
+func (app *Crawler) NavigateContext(ctx context.Context, url string, engines ...Engine) (*NavigationContext, error) {
+	engine := app.engineFor(ctx)
 	if len(engines) > 0 {
-		eng := engines[0]
-		app.overrideEngineDefaults(app.engine, &eng)
+		engine = app.layerEngine(engine, &engines[0])
+	}
+	session := crawlRequestFrom(ctx).Session
+	if session == "" {
+		session = proxySessionKey(UrlCollection{Url: url}) // Outside of a crawl the url starts its own session
 	}
+	ctx = withCrawlRequest(ctx, &crawlRequest{Engine: engine, Session: session})
 	proxy := app.getCurrentProxy()
-	if shouldRotateProxy {
-		if len(app.engine.ProxyServers) == 0 && app.engine.ProxyStrategy == ProxyStrategyRotation {
+	if app.shouldRotateProxy.Load() {
+		if len(engine.ProxyServers) == 0 && engine.ProxyStrategy == ProxyStrategyRotation {
 			app.Logger.Fatal("No proxies provided for rotation")
+			return nil, errNoProxies
 		}
 		proxyIndex := int(atomic.LoadInt32(&app.lastWorkingProxyIndex))
-		proxyIndex = (proxyIndex + 1) % len(app.engine.ProxyServers)
-		app.Logger.Summary("Error with proxy Retrying with proxy: %s", app.engine.ProxyServers[proxyIndex].Server)
-		shouldRotateProxy = false
-		proxy = app.engine.ProxyServers[proxyIndex]
+		proxyIndex = (proxyIndex + 1) % len(engine.ProxyServers)
+		app.Logger.Summary("Error with proxy Retrying with proxy: %s", engine.ProxyServers[proxyIndex].Server)
+		app.shouldRotateProxy.Store(false)
+		proxy = engine.ProxyServers[proxyIndex]
 
 		atomic.StoreInt32(&app.CurrentProxyIndex, int32(proxyIndex))
 		atomic.StoreInt32(&app.lastWorkingProxyIndex, int32(proxyIndex))
 	}
-	page := app.openPages()
+	if isStickyProxy(engine) {
+		proxy = app.proxyPool.getSticky(session)
+	}
+	page, err := app.openPages(ctx, proxy)
+	if err != nil {
+		return nil, err
+	}
+	defer app.closePages(page)
 
 	atomic.AddInt32(&app.ReqCount, 1)
 
-	// Freeze all goroutines after n requests
-	if atomic.LoadInt32(&app.ReqCount) > 0 && atomic.LoadInt32(&app.ReqCount)%int32(app.engine.SleepAfter) == 0 {
-		app.Logger.Info("Sleeping %d seconds after %d operations", app.engine.SleepDuration, app.engine.SleepAfter)
-		time.Sleep(time.Duration(app.engine.SleepDuration) * time.Second)
-	}
-
 	// Add a timeout for the navigation process
-	ctx, cancel := context.WithTimeout(context.Background(), app.engine.Timeout*2)
+	navCtx, cancel := context.WithTimeout(ctx, engine.Timeout*2)
 	defer cancel()
-	navigationContext, err := app.navigateTo(ctx, page, url, "DeepLink", false, proxy)
+	navigationContext, err := app.navigateTo(navCtx, page, url, "DeepLink", false, proxy)
 	if err != nil {
 		app.syncFailedRequestMetrics()
-		if strings.Contains(err.Error(), "StatusCode:404") {
+		if IsNotFound(err) || ctx.Err() != nil {
 			return nil, err
-		} else if strings.Contains(err.Error(), "isRetryable") {
+		} else if IsRetryable(err) {
 			// Rotate proxy if it's a retryable error
-			if len(app.engine.ProxyServers) > 0 && app.engine.ProxyStrategy == ProxyStrategyRotation {
-				shouldRotateProxy = true
-				if app.engine.RetrySleepDuration > 0 {
-					app.Logger.Info("Sleeping %d minutes before retrying", app.engine.RetrySleepDuration)
-					time.Sleep(time.Duration(app.engine.RetrySleepDuration) * time.Minute)
+			if len(engine.ProxyServers) > 0 && engine.ProxyStrategy == ProxyStrategyRotation {
+				app.shouldRotateProxy.Store(true)
+				if engine.RetrySleepDuration > 0 {
+					app.Logger.Info("Sleeping %d minutes before retrying", engine.RetrySleepDuration)
+					if err := sleepContext(ctx, time.Duration(engine.RetrySleepDuration)*time.Minute); err != nil {
+						return nil, err
+					}
 				}
 				// Retry with the next proxy and return the result
-				return app.Navigate(url, engines...)
+				return app.NavigateContext(ctx, url)
 				//return nil, err
 			}
-			if app.engine.RetrySleepDuration > 0 {
+			if engine.RetrySleepDuration > 0 {
 				app.HandleThrottling(1, 0)
 			}
 
@@ -66,62 +78,75 @@ func (app *Crawler) Navigate(url string, engines ...Engine) (*NavigationContext,
 			return nil, err
 		}
 	}
+	navigationContext.ctx = ctx
 	app.syncRequestMetrics()
-	defer app.closePages(page)
 	return navigationContext, nil
 }
 
 func (app *Crawler) Navigates(url string, fn func(*NavigationContext) error, engines ...Engine) error {
-	app.overrideEngineDefaults(app.engine, &app.CurrentProcessorConfig.Engine)
+	return app.NavigatesContext(context.Background(), url, fn, engines...)
+}
+
+// NavigatesContext is Navigates with a context, aborting the navigation and the retries once ctx is done.
+func (app *Crawler) NavigatesContext(ctx context.Context, url string, fn func(*NavigationContext) error, engines ...Engine) error {
+	engine := app.engineFor(ctx)
 	if len(engines) > 0 {
-		eng := engines[0]
-		app.overrideEngineDefaults(app.engine, &eng)
+		engine = app.layerEngine(engine, &engines[0])
+	}
+	session := crawlRequestFrom(ctx).Session
+	if session == "" {
+		session = proxySessionKey(UrlCollection{Url: url}) // Outside of a crawl the url starts its own session
 	}
+	ctx = withCrawlRequest(ctx, &crawlRequest{Engine: engine, Session: session})
 	proxy := app.getCurrentProxy()
-	if shouldRotateProxy {
-		if len(app.engine.ProxyServers) == 0 && app.engine.ProxyStrategy == ProxyStrategyRotation {
+	if app.shouldRotateProxy.Load() {
+		if len(engine.ProxyServers) == 0 && engine.ProxyStrategy == ProxyStrategyRotation {
 			app.Logger.Fatal("No proxies provided for rotation")
+			return errNoProxies
 		}
 		proxyIndex := int(atomic.LoadInt32(&app.lastWorkingProxyIndex))
-		proxyIndex = (proxyIndex + 1) % len(app.engine.ProxyServers)
-		app.Logger.Summary("Error with proxy Retrying with proxy: %s", app.engine.ProxyServers[proxyIndex].Server)
-		shouldRotateProxy = false
-		proxy = app.engine.ProxyServers[proxyIndex]
+		proxyIndex = (proxyIndex + 1) % len(engine.ProxyServers)
+		app.Logger.Summary("Error with proxy Retrying with proxy: %s", engine.ProxyServers[proxyIndex].Server)
+		app.shouldRotateProxy.Store(false)
+		proxy = engine.ProxyServers[proxyIndex]
 
 		atomic.StoreInt32(&app.CurrentProxyIndex, int32(proxyIndex))
 		atomic.StoreInt32(&app.lastWorkingProxyIndex, int32(proxyIndex))
 	}
-	page := app.openPages()
+	if isStickyProxy(engine) {
+		proxy = app.proxyPool.getSticky(session)
+	}
+	page, err := app.openPages(ctx, proxy)
+	if err != nil {
+		return err
+	}
+	defer app.closePages(page)
 
 	atomic.AddInt32(&app.ReqCount, 1)
 
-	// Freeze all goroutines after n requests
-	if atomic.LoadInt32(&app.ReqCount) > 0 && atomic.LoadInt32(&app.ReqCount)%int32(app.engine.SleepAfter) == 0 {
-		app.Logger.Info("Sleeping %d seconds after %d operations", app.engine.SleepDuration, app.engine.SleepAfter)
-		time.Sleep(time.Duration(app.engine.SleepDuration) * time.Second)
-	}
-
 	// Add a timeout for the navigation process
-	ctx, cancel := context.WithTimeout(context.Background(), app.engine.Timeout*2)
+	navCtx, cancel := context.WithTimeout(ctx, engine.Timeout*2)
 	defer cancel()
-	navigationContext, err := app.navigateTo(ctx, page, url, "DeepLink", false, proxy)
+	navigationContext, err := app.navigateTo(navCtx, page, url, "DeepLink", false, proxy)
 	if err != nil {
 		app.syncFailedRequestMetrics()
-		if strings.Contains(err.Error(), "StatusCode:404") {
+		if IsNotFound(err) || ctx.Err() != nil {
 			return err
-		} else if strings.Contains(err.Error(), "isRetryable") {
+		} else if IsRetryable(err) {
 			// Rotate proxy if it's a retryable error
-			if len(app.engine.ProxyServers) > 0 && app.engine.ProxyStrategy == ProxyStrategyRotation {
-				shouldRotateProxy = true
-				if app.engine.RetrySleepDuration > 0 {
-					app.Logger.Info("Sleeping %d minutes before retrying", app.engine.RetrySleepDuration)
-					time.Sleep(time.Duration(app.engine.RetrySleepDuration) * time.Minute)
+			if len(engine.ProxyServers) > 0 && engine.ProxyStrategy == ProxyStrategyRotation {
+				app.shouldRotateProxy.Store(true)
+				if engine.RetrySleepDuration > 0 {
+					app.Logger.Info("Sleeping %d minutes before retrying", engine.RetrySleepDuration)
+					if err := sleepContext(ctx, time.Duration(engine.RetrySleepDuration)*time.Minute); err != nil {
+						return err
+					}
 				}
 				// Retry with the next proxy and return the result
-				return app.Navigates(url, fn, engines...)
+				return app.NavigatesContext(ctx, url, fn)
 				//return nil, err
 			}
-			if app.engine.RetrySleepDuration > 0 {
+			if engine.RetrySleepDuration > 0 {
 				app.HandleThrottling(1, 0)
 			}
 
@@ -130,12 +155,12 @@ func (app *Crawler) Navigates(url string, fn func(*NavigationContext) error, eng
 			return err
 		}
 	}
+	navigationContext.ctx = ctx
 	fnErr := fn(navigationContext)
 	if fnErr != nil {
 		app.syncFailedRequestMetrics()
 		return err
 	}
 	app.syncRequestMetrics()
-	defer app.closePages(page)
 	return nil
 }
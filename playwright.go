@@ -1,9 +1,12 @@
 package ninjacrawler
 
 import (
+	"context"
 	"fmt"
 	"github.com/PuerkitoBio/goquery"
 	"github.com/playwright-community/playwright-go"
+	"go.opentelemetry.io/otel/attribute"
+	semconv "go.opentelemetry.io/otel/semconv/v1.24.0"
 )
 
 // GetPlaywright initializes and runs the Playwright framework.
@@ -19,23 +22,29 @@ func (app *Crawler) GetPlaywright() (*playwright.Playwright, error) {
 // GetBrowserPage launches a browser instance and creates a new page using the Playwright framework.
 // It supports Chromium, Firefox, and WebKit browsers, and can configure Proxy settings if provided.
 // It returns the browser and page instances, or an error if the operation fails.
+// The browser and page take the options of the running engine.
 func (app *Crawler) GetBrowserPage(pw *playwright.Playwright, browserType string, proxy Proxy) (playwright.Browser, playwright.Page, error) {
 	var browser playwright.Browser
 	var err error
+	engine := app.currentEngine()
 
 	var browserTypeLaunchOptions playwright.BrowserTypeLaunchOptions
 	browserTypeLaunchOptions.Headless = playwright.Bool(!app.isLocalEnv)
 	browserTypeLaunchOptions.Devtools = playwright.Bool(app.isLocalEnv)
 	// Set additional launch arguments
-	if len(app.engine.Args) > 0 {
-		browserTypeLaunchOptions.Args = app.engine.Args
+	if len(engine.Args) > 0 {
+		browserTypeLaunchOptions.Args = engine.Args
 	}
-	if len(app.engine.ProxyServers) > 0 && proxy.Server != "" {
+	if len(engine.ProxyServers) > 0 && proxy.Server != "" {
+		server, username, password, err := app.browserProxy(proxy)
+		if err != nil {
+			return nil, nil, err
+		}
 		// Set Proxy options
 		browserTypeLaunchOptions.Proxy = &playwright.Proxy{
-			Server:   proxy.Server,
-			Username: playwright.String(proxy.Username),
-			Password: playwright.String(proxy.Password),
+			Server:   server,
+			Username: playwright.String(username),
+			Password: playwright.String(password),
 		}
 	}
 	switch browserType {
@@ -55,21 +64,21 @@ func (app *Crawler) GetBrowserPage(pw *playwright.Playwright, browserType string
 
 	page, err := browser.NewPage(playwright.BrowserNewPageOptions{
 		UserAgent:         playwright.String(app.userAgent),
-		JavaScriptEnabled: playwright.Bool(app.engine.JavaScriptEnabled),
+		JavaScriptEnabled: playwright.Bool(engine.JavaScriptEnabled),
 	})
 	if err != nil {
 		return nil, nil, fmt.Errorf("failed to create page: %w", err)
 	}
 
 	// Conditionally intercept and block resources based on configuration
-	if app.engine.BlockResources {
+	if engine.BlockResources {
 		err := page.Route("**/*", func(route playwright.Route) {
 			req := route.Request()
 			resourceType := req.ResourceType()
 			url := req.URL()
 
 			// Check if the resource should be blocked based on resource type or URL
-			if app.shouldBlockResource(resourceType, url) {
+			if app.shouldBlockResource(engine, resourceType, url) {
 				route.Abort()
 			} else {
 				route.Continue()
@@ -83,13 +92,24 @@ func (app *Crawler) GetBrowserPage(pw *playwright.Playwright, browserType string
 	return browser, page, nil
 }
 func (app *Crawler) GetBrowser(pw *playwright.Playwright, browserType string, proxy Proxy) (playwright.BrowserContext, error) {
+	engine := *app.currentEngine()
+	engine.BrowserType = browserType
+	browser, contextOpts, err := app.launchPlaywrightBrowser(pw, &engine, proxy)
+	if err != nil {
+		return nil, err
+	}
+	return app.newPlaywrightContext(browser, contextOpts, &engine)
+}
+
+// launchPlaywrightBrowser launches a browser of engine through proxy, with the options of its contexts.
+func (app *Crawler) launchPlaywrightBrowser(pw *playwright.Playwright, engine *Engine, proxy Proxy) (playwright.Browser, playwright.BrowserNewContextOptions, error) {
 	var (
 		browser               playwright.Browser
 		err                   error
 		browserTypeLaunchOpts = playwright.BrowserTypeLaunchOptions{
 			Headless: playwright.Bool(!app.isLocalEnv),
 			Devtools: playwright.Bool(app.isLocalEnv),
-			Args:     app.engine.Args,
+			Args:     engine.Args,
 		}
 		contextOpts = playwright.BrowserNewContextOptions{
 			ExtraHttpHeaders: map[string]string{
@@ -99,15 +119,19 @@ func (app *Crawler) GetBrowser(pw *playwright.Playwright, browserType string, pr
 	)
 
 	// Set proxy options if available
-	if len(app.engine.ProxyServers) > 0 && proxy.Server != "" {
+	if len(engine.ProxyServers) > 0 && proxy.Server != "" {
+		server, username, password, err := app.browserProxy(proxy)
+		if err != nil {
+			return nil, contextOpts, err
+		}
 		browserTypeLaunchOpts.Proxy = &playwright.Proxy{
-			Server:   proxy.Server,
-			Username: playwright.String(proxy.Username),
-			Password: playwright.String(proxy.Password),
+			Server:   server,
+			Username: playwright.String(username),
+			Password: playwright.String(password),
 		}
 	}
 	// Launch the appropriate browser and configure user-agent headers
-	switch browserType {
+	switch engine.BrowserType {
 	case "chromium":
 		browser, err = pw.Chromium.Launch(browserTypeLaunchOpts)
 		setChromiumHeaders(&contextOpts, browser)
@@ -118,25 +142,31 @@ func (app *Crawler) GetBrowser(pw *playwright.Playwright, browserType string, pr
 		browser, err = pw.WebKit.Launch(browserTypeLaunchOpts)
 		setWebKitHeaders(&contextOpts, browser)
 	default:
-		return nil, fmt.Errorf("unsupported browser type: %s", browserType)
+		return nil, contextOpts, fmt.Errorf("unsupported browser type: %s", engine.BrowserType)
 	}
 
 	if err != nil {
-		return nil, fmt.Errorf("failed to launch browser: %w", err)
+		return nil, contextOpts, fmt.Errorf("failed to launch browser: %w", err)
 	}
 
 	// Overwrite the default User-Agent header
 	if app.userAgent != "" {
 		contextOpts.ExtraHttpHeaders["User-Agent"] = app.userAgent
 	}
+	return browser, contextOpts, nil
+}
+
+// newPlaywrightContext opens a fresh context in browser, with the cookies of the engine.
+func (app *Crawler) newPlaywrightContext(browser playwright.Browser, contextOpts playwright.BrowserNewContextOptions, engine *Engine) (playwright.BrowserContext, error) {
 	context, err := browser.NewContext(contextOpts)
 	if err != nil {
 		return nil, fmt.Errorf("could not create new browser context: %w", err)
 	}
 
-	if len(app.engine.Cookies) > 0 {
-		err = context.AddCookies(app.engine.Cookies)
+	if len(engine.Cookies) > 0 {
+		err = context.AddCookies(engine.Cookies)
 		if err != nil {
+			_ = context.Close()
 			return nil, fmt.Errorf("failed to add cookies: %w", err)
 		}
 	}
@@ -145,21 +175,25 @@ func (app *Crawler) GetBrowser(pw *playwright.Playwright, browserType string, pr
 }
 
 func (app *Crawler) GetPage(context playwright.BrowserContext) (playwright.Page, error) {
+	return app.getPage(context, app.currentEngine())
+}
 
+// getPage opens a page of engine in context.
+func (app *Crawler) getPage(context playwright.BrowserContext, engine *Engine) (playwright.Page, error) {
 	page, err := context.NewPage()
 	if err != nil {
 		return nil, fmt.Errorf("failed to create page: %w", err)
 	}
 
 	// Conditionally intercept and block resources based on configuration
-	if app.engine.BlockResources {
+	if engine.BlockResources {
 		err := page.Route("**/*", func(route playwright.Route) {
 			req := route.Request()
 			resourceType := req.ResourceType()
 			url := req.URL()
 
 			// Check if the resource should be blocked based on resource type or URL
-			if app.shouldBlockResource(resourceType, url) {
+			if app.shouldBlockResource(engine, resourceType, url) {
 				route.Abort()
 			} else {
 				route.Continue()
@@ -176,37 +210,68 @@ func (app *Crawler) GetPage(context playwright.BrowserContext) (playwright.Page,
 // It waits until the page is fully loaded, handles cookie consent, and returns a goquery document representing the DOM.
 // If navigation or handling consent fails, it logs the page content to a file and returns an error.
 func (app *Crawler) NavigateToURL(pageInterFace interface{}, url string, proxy Proxy) (playwright.Page, *goquery.Document, error) {
+	return app.navigateToURL(context.Background(), pageInterFace, url, proxy)
+}
+
+// playwrightFetcher is the "playwright" provider, navigating the Playwright page of the request.
+// Unsuccessful status codes are returned as errors.
+type playwrightFetcher struct {
+	app *Crawler
+}
+
+func (f *playwrightFetcher) Fetch(ctx context.Context, req *FetchRequest) (*FetchResponse, error) {
+	page, document, err := f.app.navigateToURL(ctx, req.Page, req.Url, req.Proxy)
+	if err != nil {
+		return nil, err
+	}
+	return browserResponse(page, page.URL(), document), nil
+}
+
+func (app *Crawler) navigateToURL(ctx context.Context, pageInterFace interface{}, url string, proxy Proxy) (playwright.Page, *goquery.Document, error) {
+	engine := app.engineFor(ctx)
 	var page playwright.Page
 	page = pageInterFace.(playwright.Page)
 	originalURL := url // Store the original URL for comparison
 	pageGotoOptions := playwright.PageGotoOptions{
-		Timeout: playwright.Float(float64(app.engine.Timeout.Milliseconds())),
+		Timeout: playwright.Float(float64(engine.Timeout.Milliseconds())),
 	}
-	if app.engine.WaitForDynamicRendering && app.engine.WaitForSelector == nil {
+	if engine.WaitForDynamicRendering && engine.WaitForSelector == nil {
 		pageGotoOptions.WaitUntil = playwright.WaitUntilStateNetworkidle
 	}
 
+	if err := app.waitForRateLimit(ctx, url); err != nil {
+		return nil, nil, err
+	}
 	// Navigate to the URL
+	_, gotoSpan := app.startSpan(ctx, "goto", semconv.URLFull(url))
+	// Playwright has no context support, closing the page aborts the navigation when ctx is done
+	stopClose := context.AfterFunc(ctx, func() { _ = page.Close() })
 	res, err := page.Goto(url, pageGotoOptions)
+	stopClose()
 	if err != nil {
-		d, e := app.handleProxyError(proxy, err)
+		d, e := app.handleProxyError(ctx, proxy, err)
+		endSpan(gotoSpan, e)
 		return nil, d, e
 	}
+	gotoSpan.SetAttributes(semconv.HTTPResponseStatusCode(res.Status()))
 	if !res.Ok() {
-		return nil, nil, app.handleHttpError(res.Status(), res.StatusText(), url, page)
+		httpErr := app.handleHttpError(ctx, res.Status(), res.StatusText(), url, page)
+		endSpan(gotoSpan, httpErr)
+		return nil, nil, httpErr
 	}
+	gotoSpan.End()
 
 	// Check for redirection
 	finalURL := page.URL()
-	if originalURL != finalURL && *app.engine.TrackRedirection {
-		app.CurrentUrl = finalURL
-		_ = app.updateRedirection(originalURL, finalURL)
+	if originalURL != finalURL && *engine.TrackRedirection {
+		crawlRequestFrom(ctx).Url = finalURL
+		_ = app.updateRedirection(ctx, originalURL, finalURL)
 		app.Logger.Warn(fmt.Sprintf("Redirection detected: %s -> %s", originalURL, finalURL))
 	}
 
 	// Handle cookie consent
-	if err = handleCookieConsent(page, app.engine.CookieConsent); err != nil {
-		if app.engine.CookieConsent.IsOptional {
+	if err = handleCookieConsent(page, engine.CookieConsent); err != nil {
+		if engine.CookieConsent.IsOptional {
 			app.Logger.Warn("cookie consent not found: %s", err.Error())
 		} else {
 			app.Logger.Html(app.getHtmlFromPage(page), url, err.Error())
@@ -214,31 +279,33 @@ func (app *Crawler) NavigateToURL(pageInterFace interface{}, url string, proxy P
 		}
 	}
 	// Handle mouse simulation, if applicable
-	if app.engine.SimulateMouse != nil && *app.engine.SimulateMouse {
+	if engine.SimulateMouse != nil && *engine.SimulateMouse {
 		if mError := autoMoveMouse(page); mError != nil {
 			app.Logger.Error("Mouse Simulate Error: %s", mError.Error())
 		}
 	}
 
 	// Wait for selector if applicable
-	if app.engine.WaitForSelector != nil || app.engine.WaitForSelectorVisible != nil {
+	if engine.WaitForSelector != nil || engine.WaitForSelectorVisible != nil {
 		selector := ""
-		if app.engine.WaitForSelector != nil {
-			selector = *app.engine.WaitForSelector
+		if engine.WaitForSelector != nil {
+			selector = *engine.WaitForSelector
 		}
 		pageWaitForSelectorOptions := playwright.PageWaitForSelectorOptions{
-			Timeout: playwright.Float(float64(app.engine.Timeout.Milliseconds())),
+			Timeout: playwright.Float(float64(engine.Timeout.Milliseconds())),
 			State:   playwright.WaitForSelectorStateAttached,
 		}
-		if app.engine.WaitForSelectorVisible != nil {
+		if engine.WaitForSelectorVisible != nil {
 			pageWaitForSelectorOptions.State = playwright.WaitForSelectorStateVisible
-			pageWaitForSelectorOptions.Timeout = playwright.Float(float64(app.engine.Timeout.Milliseconds()))
-			selector = *app.engine.WaitForSelectorVisible
+			pageWaitForSelectorOptions.Timeout = playwright.Float(float64(engine.Timeout.Milliseconds()))
+			selector = *engine.WaitForSelectorVisible
 		}
+		_, waitSpan := app.startSpan(ctx, "wait_for_selector", attribute.String("selector", selector))
 		_, err = page.WaitForSelector(selector, pageWaitForSelectorOptions)
+		endSpan(waitSpan, err)
 		if err != nil {
 			app.Logger.Html(app.getHtmlFromPage(page), url, fmt.Sprintf("Failed to find %s: %s", selector, err.Error()))
-			if *app.engine.IsWaitForSelectorOptional {
+			if *engine.IsWaitForSelectorOptional {
 				app.Logger.Warn("%s Not found found in DOM: %s", selector, err.Error())
 			} else {
 				return nil, nil, fmt.Errorf("failed to find %s: %w", selector, err)
@@ -251,13 +318,13 @@ func (app *Crawler) NavigateToURL(pageInterFace interface{}, url string, proxy P
 		return nil, nil, fmt.Errorf("failed to get page DOM: %w", err)
 	}
 
-	if app.engine.SendHtmlToBigquery != nil && *app.engine.SendHtmlToBigquery {
-		sendErr := app.SendHtmlToBigquery(document, url)
+	if engine.SendHtmlToBigquery != nil && *engine.SendHtmlToBigquery {
+		sendErr := app.SendHtmlToBigqueryContext(ctx, document, url)
 		if sendErr != nil {
 			app.Logger.Error("SendHtmlToBigquery Error: %s", sendErr.Error())
 		}
 	}
-	if *app.engine.StoreHtml {
+	if *engine.StoreHtml {
 		if StoreHtmlErr := app.SaveHtml(document, url); StoreHtmlErr != nil {
 			app.Logger.Error(StoreHtmlErr.Error())
 		}
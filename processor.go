@@ -1,17 +1,36 @@
 package ninjacrawler
 
 import (
+	"context"
 	"strconv"
 	"sync"
 	"sync/atomic"
 )
 
 func (app *Crawler) Crawl(configs []ProcessorConfig) {
+	app.CrawlContext(context.Background(), configs)
+}
+
+// CrawlContext is Crawl with a context. Once ctx is done no new urls are handed out,
+// and the requests, navigations and database calls in flight are aborted. Aborted urls stay pending.
+func (app *Crawler) CrawlContext(ctx context.Context, configs []ProcessorConfig) {
+	app.registerCollectionIndexes(configs)
 	for _, config := range configs {
+		if app.isInterrupted() || ctx.Err() != nil {
+			break
+		}
 		app.Logger.Summary("Starting: %s Crawler", config.OriginCollection)
-		app.overrideEngineDefaults(app.engine, &config.Engine)
+		engine := app.resolveEngine(&config.Engine)
+		app.engine.Store(engine)
+		app.warnInsecureEngine(config.OriginCollection, engine)
+		if _, err := app.fetcherFor(engine); err != nil {
+			app.Logger.Fatal("%s: %v", config.OriginCollection, err)
+			return
+		}
 
-		app.CurrentProcessorConfig = config
+		current := config
+		app.processor.Store(&current)
+		app.trackProcessor(config)
 		var total int32 = 0
 		dataCount, _ := strconv.Atoi(app.GetDataCount(config.Entity))
 		if dataCount > 0 {
@@ -21,31 +40,38 @@ func (app *Crawler) Crawl(configs []ProcessorConfig) {
 
 		for {
 			var productList []UrlCollection
+			batchEngine := engine
 			if config.Preference.ValidationRetryConfig != nil {
-				retryableProductLists := app.getRetryableUrlCollections(config.OriginCollection)
+				retryableProductLists := app.getRetryableUrlCollections(ctx, config.OriginCollection)
 				if len(retryableProductLists) > 0 {
 					productList = retryableProductLists
-					app.overrideEngineDefaults(app.engine, config.Preference.ValidationRetryConfig)
+					batchEngine = app.resolveEngine(&config.Engine, config.Preference.ValidationRetryConfig)
 				} else {
-					productList = app.getUrlCollections(config.OriginCollection)
+					productList = app.getUrlCollections(ctx, config.OriginCollection)
 				}
 			} else {
-				productList = app.getUrlCollections(config.OriginCollection)
+				productList = app.getUrlCollections(ctx, config.OriginCollection)
 			}
 			if len(productList) == 0 {
 				break
 			}
+			// The batch engine travels in the context, the engine of the processor is never swapped mid-crawl
+			batchCtx := withCrawlRequest(ctx, &crawlRequest{Engine: batchEngine})
 
 			shouldContinue := false
-			if app.engine.ProxyStrategy == ProxyStrategyRotationPerBatch {
-				if *app.engine.IsDynamic {
+			if batchEngine.ProxyStrategy == ProxyStrategyRotationPerBatch {
+				if *batchEngine.IsDynamic {
 					app.Logger.Fatal("Dynamic mode is not supported with current strategy")
+					return
 				}
-				shouldContinue = app.processStaticUrlsWithProxies(productList, config, &total, crawlLimit)
+				shouldContinue = app.processStaticUrlsWithProxies(batchCtx, productList, config, &total, crawlLimit)
 			} else {
-				shouldContinue = app.processUrlsWithProxies(productList, config, &total, crawlLimit)
+				shouldContinue = app.processUrlsWithProxies(batchCtx, productList, config, &total, crawlLimit)
 			}
 
+			if app.structureChanged(config.Entity) || app.isInterrupted() || ctx.Err() != nil {
+				break
+			}
 			if !shouldContinue {
 				app.Logger.Debug("Crawl limit of %d reached, stopping...", crawlLimit)
 				break
@@ -57,38 +83,45 @@ func (app *Crawler) Crawl(configs []ProcessorConfig) {
 	}
 }
 
-func (app *Crawler) processUrlsWithProxies(urls []UrlCollection, config ProcessorConfig, total *int32, crawlLimit int) bool {
+// currentProcessor returns the config of the running processor, an empty config before the first one.
+func (app *Crawler) currentProcessor() ProcessorConfig {
+	if config := app.processor.Load(); config != nil {
+		return *config
+	}
+	return ProcessorConfig{}
+}
+
+func (app *Crawler) processUrlsWithProxies(ctx context.Context, urls []UrlCollection, config ProcessorConfig, total *int32, crawlLimit int) bool {
+	app.ensureHttpClient()
 	var wg sync.WaitGroup
-	proxies := app.engine.ProxyServers
+	engine := app.engineFor(ctx)
 	shouldContinue := true
 	var batchCount int32 = 0
 	var proxyLock sync.Mutex // Mutex to lock proxy access
 
-	for batchIndex := 0; batchIndex < len(urls); batchIndex += app.engine.ConcurrentLimit {
+	for batchIndex := 0; batchIndex < len(urls); batchIndex += engine.ConcurrentLimit {
 
 		if !shouldContinue {
 			break
 		}
 		proxy := Proxy{}
-		proxy = app.getProxy(int(atomic.LoadInt32(&batchCount)), proxies, &proxyLock)
-
-		app.openBrowsers(proxy)
+		proxy = app.getProxy(engine, int(atomic.LoadInt32(&batchCount)), &proxyLock)
 
 		// Loop through the URLs in the current batch
-		for i := batchIndex; i < batchIndex+app.engine.ConcurrentLimit && i < len(urls); i++ {
+		for i := batchIndex; i < batchIndex+engine.ConcurrentLimit && i < len(urls); i++ {
 			if crawlLimit > 0 && atomic.LoadInt32(total) >= int32(crawlLimit) {
 				shouldContinue = false
 				break
 			}
+			if app.structureChanged(config.Entity) || app.isInterrupted() || ctx.Err() != nil {
+				shouldContinue = false
+				break
+			}
 
 			collection := urls[i]
 
-			if app.preference.CheckRobotsTxt != nil && *app.preference.CheckRobotsTxt {
-				if !shouldCrawl(collection.Url, app.robotsData, app.GetUserAgent()) {
-					app.Logger.Debug("[SKIP] Robots disallowed: %s", collection.Url)
-					continue
-				}
-				// do check other stuff like CrawlDelay
+			if app.shouldSkipURL(ctx, collection.Url, config.OriginCollection) {
+				continue
 			}
 
 			wg.Add(1)
@@ -100,14 +133,18 @@ func (app *Crawler) processUrlsWithProxies(urls []UrlCollection, config Processo
 						app.HandlePanic(r)
 					}
 				}()
-				app.applySleep()
 				atomic.AddInt32(&app.ReqCount, 1)
-				app.CurrentCollection = config.OriginCollection
-				app.CurrentUrlCollection = urlCollection
 				//app.assignProxy(proxy)
-				page := app.openPages()
+				proxy = app.sessionProxy(ctx, urlCollection, proxy)
+				page, err := app.openPages(ctx, proxy)
+				if err != nil {
+					if ctx.Err() == nil {
+						app.Logger.Fatal("%v", err)
+					}
+					return
+				}
 				defer app.closePages(page)
-				ok := app.crawlWithProxies(page, urlCollection, config, 0, proxy)
+				ok := app.crawlWithProxies(ctx, page, urlCollection, config, 0, proxy)
 				if ok && crawlLimit > 0 && atomic.AddInt32(total, 1) > int32(crawlLimit) {
 					atomic.AddInt32(total, -1)
 					shouldContinue = false
@@ -120,7 +157,6 @@ func (app *Crawler) processUrlsWithProxies(urls []UrlCollection, config Processo
 		wg.Wait()
 		//proxyLock.Lock()
 
-		app.closeBrowsers()
 		atomic.AddInt32(&batchCount, 1)
 
 	}
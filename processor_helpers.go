@@ -1,37 +1,37 @@
 package ninjacrawler
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"github.com/temoto/robotstxt"
-	"math/rand"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 	"net/url"
 	"strconv"
-	"strings"
 	"sync"
 	"sync/atomic"
 	"time"
 )
 
-var locker sync.Mutex // Mutex to lock proxy access
-var shouldRotateProxy = false
-
 func (app *Crawler) getCrawlLimit() int {
-	if app.isLocalEnv && app.engine.DevCrawlLimit > 0 {
-		return app.engine.DevCrawlLimit
-	} else if app.isStgEnv && app.engine.StgCrawlLimit > 0 {
-		return app.engine.StgCrawlLimit
+	if app.isLocalEnv && app.currentEngine().DevCrawlLimit > 0 {
+		return app.currentEngine().DevCrawlLimit
+	} else if app.isStgEnv && app.currentEngine().StgCrawlLimit > 0 {
+		return app.currentEngine().StgCrawlLimit
 	}
 	return 0
 }
 
 func (app *Crawler) batchAbleStrategy() bool {
-	return app.engine.ProxyStrategy == ProxyStrategyRotationPerBatch
+	return app.currentEngine().ProxyStrategy == ProxyStrategyRotationPerBatch
 }
-func (app *Crawler) getProxy(batchCount int, proxies []Proxy, proxyLock *sync.Mutex) Proxy {
+func (app *Crawler) getProxy(engine *Engine, batchCount int, proxyLock *sync.Mutex) Proxy {
+	proxies := engine.ProxyServers
 	proxyLock.Lock()
 	defer proxyLock.Unlock()
-	if len(app.engine.ProxyServers) == 0 && app.engine.ProxyStrategy == ProxyStrategyRotation {
+	if len(engine.ProxyServers) == 0 && engine.ProxyStrategy == ProxyStrategyRotation {
 		app.Logger.Fatal("No proxies provided for rotation")
 	}
 	if len(proxies) == 0 {
@@ -39,73 +39,80 @@ func (app *Crawler) getProxy(batchCount int, proxies []Proxy, proxyLock *sync.Mu
 	}
 	proxyIndex := 0
 	var proxy Proxy
-	if app.engine.ProxyStrategy == ProxyStrategyConcurrency {
+	if engine.ProxyStrategy == ProxyStrategyConcurrency {
 		proxyIndex = int(atomic.LoadInt32(&app.lastWorkingProxyIndex))
 		proxyIndex = (proxyIndex + 1) % len(proxies)
-		shouldRotateProxy = false
+		app.shouldRotateProxy.Store(false)
 		if batchCount == 0 {
 			proxyIndex = 0
 		}
 		proxy = proxies[proxyIndex]
-	} else if app.engine.ProxyStrategy == ProxyStrategyRotation {
+	} else if engine.ProxyStrategy == ProxyStrategyRotation {
 		proxyIndex = int(atomic.LoadInt32(&app.lastWorkingProxyIndex))
-		if shouldRotateProxy {
+		if app.shouldRotateProxy.Load() {
 			proxyIndex = (proxyIndex + 1) % len(proxies)
-			app.Logger.Summary("Error with proxy %s: Retrying with proxy: %s", app.engine.ProxyServers[atomic.LoadInt32(&app.lastWorkingProxyIndex)].Server, app.engine.ProxyServers[proxyIndex].Server)
-			shouldRotateProxy = false
+			app.Logger.Summary("Error with proxy %s: Retrying with proxy: %s", engine.ProxyServers[atomic.LoadInt32(&app.lastWorkingProxyIndex)].Server, engine.ProxyServers[proxyIndex].Server)
+			app.shouldRotateProxy.Store(false)
 		}
 		proxy = proxies[proxyIndex]
-	} else if app.engine.ProxyStrategy == ProxyStrategyRotationPerBatch {
+	} else if engine.ProxyStrategy == ProxyStrategyRotationPerBatch {
 		proxyIndex = int(atomic.LoadInt32(&app.lastWorkingProxyIndex))
 		proxyIndex = (proxyIndex + 1) % len(proxies)
-		shouldRotateProxy = false
+		app.shouldRotateProxy.Store(false)
 		if batchCount == 0 {
 			proxyIndex = 0
 		}
 		proxy = proxies[proxyIndex]
 	}
+	if app.proxyPool.isBenched(proxy) {
+		next := app.proxyPool.getNext()
+		app.Logger.Debug("Proxy %s is cooling down, using %s", proxy.Server, next.Server)
+		proxy = next
+		proxyIndex = indexOfProxy(proxies, proxy)
+	}
 	atomic.StoreInt32(&app.CurrentProxyIndex, int32(proxyIndex))
 	atomic.StoreInt32(&app.lastWorkingProxyIndex, int32(proxyIndex))
 	return proxy
 }
-func (app *Crawler) applySleep() {
-	// Random sleep between 500ms and 1s
-
-	if app.engine.ApplyRandomSleep != nil && *app.engine.ApplyRandomSleep {
-		sleepDuration := time.Duration(rand.Intn(501)+500) * time.Millisecond
-		time.Sleep(sleepDuration)
-	}
-	if atomic.LoadInt32(&app.ReqCount) > 0 && atomic.LoadInt32(&app.ReqCount)%int32(app.engine.SleepAfter) == 0 {
-		app.Logger.Info("Sleeping %d seconds after %d operations", app.engine.SleepDuration, app.engine.SleepAfter)
-		time.Sleep(time.Duration(app.engine.SleepDuration) * time.Second)
-	}
-}
-
 func (app *Crawler) assignProxy(proxy Proxy) {
-	if len(app.engine.ProxyServers) == 0 && app.engine.ProxyStrategy == ProxyStrategyRotation {
+	if len(app.currentEngine().ProxyServers) == 0 && app.currentEngine().ProxyStrategy == ProxyStrategyRotation {
 		app.Logger.Fatal("No proxies available")
 		return
 	}
-	if len(app.engine.ProxyServers) > 0 {
-		app.CurrentProxy = proxy
-		proxyIndex := atomic.LoadInt32(&app.CurrentProxyIndex) % int32(len(app.engine.ProxyServers))
+	if len(app.currentEngine().ProxyServers) > 0 {
+		proxyIndex := atomic.LoadInt32(&app.CurrentProxyIndex) % int32(len(app.currentEngine().ProxyServers))
 		atomic.StoreInt32(&app.CurrentProxyIndex, proxyIndex)
 	}
 }
 
-func (app *Crawler) crawlWithProxies(page interface{}, urlCollection UrlCollection, config ProcessorConfig, attempt int, proxy Proxy) bool {
+func (app *Crawler) crawlWithProxies(ctx context.Context, page interface{}, urlCollection UrlCollection, config ProcessorConfig, attempt int, proxy Proxy) bool {
 	//fmt.Println("CurrentProxyIndex", atomic.LoadInt32(&app.CurrentProxyIndex))
 	if app.runPreHandlers(config, urlCollection) {
-		ctx, err := app.handleCrawlWorker(page, config, urlCollection, proxy)
+		spanCtx, span := app.startSpan(ctx, "crawl", append(urlAttributes(urlCollection.Url, config.OriginCollection, proxy),
+			attribute.Int("attempt", attempt))...)
+		crawlerCtx, err := app.handleCrawlWorker(spanCtx, page, config, urlCollection, proxy)
 		if err != nil {
+			endSpan(span, err)
+			if isNotModified(err) {
+				return app.handleCrawlError(ctx, err, urlCollection, config, attempt) // Unchanged pages are no failed requests
+			}
 			app.syncFailedRequestMetrics()
-			return app.handleCrawlError(err, urlCollection, config, attempt)
+			if ctx.Err() != nil {
+				return false // Cancelled, the url stays pending
+			}
+			return app.handleCrawlError(ctx, err, urlCollection, config, attempt)
 		}
-		errExtract := app.extract(page, config, *ctx)
+		start := time.Now()
+		var extractSpan trace.Span
+		crawlerCtx.ctx, extractSpan = app.startSpan(crawlerCtx.ctx, "extract", attribute.String("processor", fmt.Sprintf("%T", config.Processor)))
+		errExtract := app.extract(page, config, *crawlerCtx)
+		endSpan(extractSpan, errExtract)
+		endSpan(span, errExtract)
+		app.observeExtraction(config.OriginCollection, start)
 		if errExtract != nil {
 			app.syncFailedRequestMetrics()
-			if strings.Contains(errExtract.Error(), "isRetryable") {
-				return app.rotateProxy(errExtract, attempt)
+			if IsRetryable(errExtract) {
+				return app.rotateProxy(ctx, errExtract, attempt)
 			}
 			app.Logger.Error(errExtract.Error())
 			return false
@@ -126,38 +133,58 @@ func (app *Crawler) runPreHandlers(config ProcessorConfig, urlCollection UrlColl
 	return true
 }
 
-func (app *Crawler) handleCrawlError(err error, urlCollection UrlCollection, config ProcessorConfig, attempt int) bool {
-	if strings.Contains(err.Error(), "StatusCode:404") {
-		if markMaxErr := app.MarkAsMaxErrorAttempt(urlCollection.Url, config.OriginCollection, err.Error()); markMaxErr != nil {
-			app.Logger.Error("markMaxErr: ", markMaxErr.Error())
+func (app *Crawler) handleCrawlError(ctx context.Context, err error, urlCollection UrlCollection, config ProcessorConfig, attempt int) bool {
+	logger := app.Logger.With("collection", config.OriginCollection, "url", urlCollection.Url, "attempt", attempt)
+	var notModifiedErr *NotModifiedError
+	if errors.As(err, &notModifiedErr) {
+		logger.Info("Unchanged since last crawl")
+		if isProductProcessor(config) {
+			app.markProductUnchanged(ctx, config.Entity, urlCollection.Url)
+		}
+		if markErr := app.markAsComplete(ctx, urlCollection.Url, config.OriginCollection); markErr != nil {
+			logger.Error(markErr.Error())
+		}
+		return false
+	}
+	var skippedErr *SkippedError
+	if errors.As(err, &skippedErr) {
+		if markErr := app.markAsSkipped(ctx, urlCollection.Url, config.OriginCollection, skippedErr.Reason); markErr != nil {
+			logger.Error(markErr.Error())
+		}
+		return false
+	}
+	if IsNotFound(err) {
+		if markMaxErr := app.MarkAsMaxErrorAttemptContext(ctx, urlCollection.Url, config.OriginCollection, err.Error()); markMaxErr != nil {
+			logger.Error("markMaxErr: %v", markMaxErr)
 			return false
 		}
 	}
 
-	if markErr := app.MarkAsError(urlCollection.Url, config.OriginCollection, err.Error()); markErr != nil {
-		app.Logger.Error("markErr: ", markErr.Error())
+	if markErr := app.MarkAsErrorContext(ctx, urlCollection.Url, config.OriginCollection, err.Error()); markErr != nil {
+		logger.Error("markErr: %v", markErr)
 	}
-	app.Logger.Error("Error crawling %s: %v", urlCollection.Url, err)
+	logger.Error("Error crawling: %v", err)
 
-	if strings.Contains(err.Error(), "isRetryable") {
-		return app.rotateProxy(err, attempt)
+	if IsRetryable(err) {
+		return app.rotateProxy(ctx, err, attempt)
 	}
 	return false
 }
 
-func (app *Crawler) rotateProxy(err error, attempt int) bool {
-	if app.engine.RetrySleepDuration > 0 {
-		app.Logger.Info("Sleeping %d minutes before retrying", app.engine.RetrySleepDuration)
-		time.Sleep(time.Duration(app.engine.RetrySleepDuration) * time.Minute)
+func (app *Crawler) rotateProxy(ctx context.Context, err error, attempt int) bool {
+	engine := app.engineFor(ctx)
+	if engine.RetrySleepDuration > 0 {
+		app.Logger.Info("Sleeping %d minutes before retrying", engine.RetrySleepDuration)
+		time.Sleep(time.Duration(engine.RetrySleepDuration) * time.Minute)
 	}
-	if len(app.engine.ProxyServers) == 0 || app.engine.ProxyStrategy != ProxyStrategyRotation {
+	if len(engine.ProxyServers) == 0 || engine.ProxyStrategy != ProxyStrategyRotation {
 		return false
 	}
 
 	app.Logger.Warn("Retrying after %d requests with different proxy %s", atomic.LoadInt32(&app.ReqCount), err.Error())
-	shouldRotateProxy = true
+	app.shouldRotateProxy.Store(true)
 
-	if attempt >= len(app.engine.ProxyServers) {
+	if attempt >= len(engine.ProxyServers) {
 		app.Logger.Info("All proxies exhausted.")
 		return true
 	}
@@ -171,12 +198,14 @@ func (app *Crawler) logSummary(config ProcessorConfig) {
 		ErrorMessage string `json:"error_message" bson:"error_message"`
 	}
 	type CrawlingSummary struct {
-		SiteID         string          `json:"site_id" bson:"site_id"`
-		CollectionName string          `json:"collection_name" bson:"collection_name"`
-		DataCount      int32           `json:"data_count" bson:"data_count"`
-		ErrorCount     int32           `json:"error_count" bson:"error_count"`
-		Errors         []CrawlingError `json:"errors" bson:"errors"`
-		CreatedAt      time.Time       `json:"created_at" bson:"created_at"`
+		SiteID          string           `json:"site_id" bson:"site_id"`
+		CollectionName  string           `json:"collection_name" bson:"collection_name"`
+		DataCount       int32            `json:"data_count" bson:"data_count"`
+		ErrorCount      int32            `json:"error_count" bson:"error_count"`
+		Errors          []CrawlingError  `json:"errors" bson:"errors"`
+		StructureAlerts []StructureAlert `json:"structure_alerts,omitempty" bson:"structure_alerts,omitempty"`
+		ProxyStats      []ProxyStats     `json:"proxy_stats,omitempty" bson:"proxy_stats,omitempty"`
+		CreatedAt       time.Time        `json:"created_at" bson:"created_at"`
 	}
 	var crawlingErrors []CrawlingError
 	dataCount := app.GetDataCount(config.Entity)
@@ -192,12 +221,15 @@ func (app *Crawler) logSummary(config ProcessorConfig) {
 
 	dataCountInt, _ := strconv.Atoi(app.GetDataCount(config.OriginCollection))
 	summary := CrawlingSummary{
-		SiteID:         app.Name,
-		CollectionName: config.OriginCollection,
-		DataCount:      int32(dataCountInt),
-		ErrorCount:     int32(len(errData)),
-		Errors:         crawlingErrors,
+		SiteID:          app.Name,
+		CollectionName:  config.OriginCollection,
+		DataCount:       int32(dataCountInt),
+		ErrorCount:      int32(len(errData)),
+		Errors:          crawlingErrors,
+		StructureAlerts: app.checkStructure(config.Entity),
+		ProxyStats:      app.proxyPool.stats(),
 	}
+	app.logProxyStats(summary.ProxyStats)
 	payloadBytes, err := json.Marshal(summary)
 	if err != nil {
 		app.Logger.Error("Failed to marshal summary report: %v", err)
@@ -208,11 +240,21 @@ func (app *Crawler) logSummary(config ProcessorConfig) {
 		app.Logger.Error("Failed to send crawling summary to Crawl Manager API: %v", err)
 	}
 }
-func (app *Crawler) processPostCrawl(config ProcessorConfig) {
+
+// isProductProcessor reports whether the processor stores product details rather than url collections.
+func isProductProcessor(config ProcessorConfig) bool {
 	switch config.Processor.(type) {
 	case ProductDetailSelector, ProductDetailApi, func(CrawlerContext, func([]ProductDetailSelector, string)) error:
+		return true
+	}
+	return false
+}
+
+func (app *Crawler) processPostCrawl(config ProcessorConfig) {
+	if isProductProcessor(config) {
 		dataCount := app.GetDataCount(config.Entity)
 		app.Logger.Summary("Data count: %s", dataCount)
+		app.logProductChanges(config.Entity)
 		exportProductDetailsToCSV(app, config.Entity, 1)
 	}
 }
@@ -352,7 +394,7 @@ func (app *Crawler) handleMonitoringReport() {
             `, totalRequests, failedRequests, successRate, elapsed, hourRate, dayRate, monthlyForecast)
 
 	// Log the consolidated report
-	app.Logger.monitoring(log)
+	app.Logger.With("report", "monitoring").Summary("%s", log)
 	if err = app.AddCrawlingLog(payload); err != nil {
 		app.Logger.Error("Failed to send monitoring report to Crawl Manager API: %v", err)
 	}
@@ -5,14 +5,14 @@ import (
 	"errors"
 	"fmt"
 	"golang.org/x/sync/errgroup"
-	"sync"
 	"sync/atomic"
 )
 
-func (app *Crawler) processStaticUrlsWithProxies(urls []UrlCollection, config ProcessorConfig, total *int32, crawlLimit int) bool {
+func (app *Crawler) processStaticUrlsWithProxies(ctx context.Context, urls []UrlCollection, config ProcessorConfig, total *int32, crawlLimit int) bool {
 	if len(urls) == 0 {
 		return true
 	}
+	app.ensureHttpClient()
 	// Constants and channels
 	const bufferSize = 100
 	urlChan := make(chan UrlCollection, bufferSize)
@@ -20,7 +20,7 @@ func (app *Crawler) processStaticUrlsWithProxies(urls []UrlCollection, config Pr
 	defer close(done)
 
 	// Error group for better error handling
-	g, ctx := errgroup.WithContext(context.Background())
+	g, ctx := errgroup.WithContext(ctx)
 	ctx, cancel := context.WithCancel(ctx)
 	defer cancel()
 
@@ -45,8 +45,8 @@ func (app *Crawler) processStaticUrlsWithProxies(urls []UrlCollection, config Pr
 	})
 
 	// Worker pool
-	proxyPool := newProxyPool(app.engine.ProxyServers)
-	for i := 0; i < app.engine.ConcurrentLimit; i++ {
+	proxyPool := app.proxyPool
+	for i := 0; i < app.engineFor(ctx).ConcurrentLimit; i++ {
 		g.Go(func() error {
 			return app.worker(ctx, urlChan, &batchCount, total, crawlLimit, config, proxyPool, &shouldContinue)
 		})
@@ -60,32 +60,6 @@ func (app *Crawler) processStaticUrlsWithProxies(urls []UrlCollection, config Pr
 	return shouldContinue.Load()
 }
 
-type ProxyPool struct {
-	proxies []Proxy
-	mu      sync.Mutex
-	current int
-}
-
-func newProxyPool(proxies []Proxy) *ProxyPool {
-	return &ProxyPool{
-		proxies: proxies,
-		current: 0,
-	}
-}
-
-func (p *ProxyPool) getNext() Proxy {
-	p.mu.Lock()
-	defer p.mu.Unlock()
-
-	if len(p.proxies) == 0 {
-		return Proxy{}
-	}
-
-	proxy := p.proxies[p.current]
-	p.current = (p.current + 1) % len(p.proxies)
-	return proxy
-}
-
 func (app *Crawler) worker(
 	ctx context.Context,
 	urlChan <-chan UrlCollection,
@@ -102,7 +76,7 @@ func (app *Crawler) worker(
 			return ctx.Err()
 		default:
 			if err := app.processURL(ctx, urlCollection, batchCount, total, crawlLimit, config, proxyPool, shouldContinue); err != nil {
-				if errors.Is(err, ErrCrawlLimitReached) {
+				if errors.Is(err, ErrCrawlLimitReached) || errors.Is(err, ErrStructureChanged) || errors.Is(err, ErrInterrupted) {
 					return nil
 				}
 				app.Logger.Error("URL processing error: %v", err)
@@ -128,9 +102,20 @@ func (app *Crawler) processURL(
 		shouldContinue.Store(false)
 		return ErrCrawlLimitReached
 	}
+	if app.structureChanged(config.Entity) {
+		shouldContinue.Store(false)
+		return ErrStructureChanged
+	}
+	if app.isInterrupted() {
+		shouldContinue.Store(false)
+		return ErrInterrupted
+	}
+	if err := ctx.Err(); err != nil {
+		shouldContinue.Store(false)
+		return err
+	}
 
-	if app.shouldSkipURL(urlCollection.Url) {
-		app.Logger.Debug("[SKIP] Robots disallowed: %s", urlCollection.Url)
+	if app.shouldSkipURL(ctx, urlCollection.Url, config.OriginCollection) {
 		return nil
 	}
 
@@ -154,24 +139,26 @@ func (app *Crawler) safeCrawl(
 		}
 	}()
 
-	proxy := proxyPool.getNext()
+	var proxy Proxy
+	if isStickyProxy(app.engineFor(ctx)) {
+		proxy = proxyPool.getSticky(proxySessionKey(urlCollection))
+	} else {
+		proxy = proxyPool.getNext()
+	}
 	batchCount.Add(1)
 
-	app.openBrowsers(proxy)
-	defer func() {
-		app.closeBrowsers()
-	}()
-
-	app.applySleep()
-
 	atomic.AddInt32(&app.ReqCount, 1)
-	app.CurrentCollection = config.OriginCollection
-	app.CurrentUrlCollection = urlCollection
 
-	page := app.openPages()
+	page, err := app.openPages(ctx, proxy)
+	if err != nil {
+		if ctx.Err() == nil {
+			app.Logger.Fatal("%v", err)
+		}
+		return err
+	}
 	defer app.closePages(page)
 
-	ok := app.crawlWithProxies(page, urlCollection, config, 0, proxy)
+	ok := app.crawlWithProxies(ctx, page, urlCollection, config, 0, proxy)
 	if ok && crawlLimit > 0 {
 		if atomic.AddInt32(total, 1) >= int32(crawlLimit) {
 			atomic.AddInt32(total, -1)
@@ -185,8 +172,14 @@ func (app *Crawler) safeCrawl(
 	return nil
 }
 
-func (app *Crawler) shouldSkipURL(url string) bool {
-	return app.preference.CheckRobotsTxt != nil &&
-		*app.preference.CheckRobotsTxt &&
-		!shouldCrawl(url, app.robotsData, app.GetUserAgent())
+// shouldSkipURL reports whether url is disallowed by robots.txt and records the skip on its url collection.
+func (app *Crawler) shouldSkipURL(ctx context.Context, url string, collection string) bool {
+	if app.isAllowedByRobots(url) {
+		return false
+	}
+	app.Logger.Warn("[SKIP] %s: %s", skipReasonRobotsTxt, url)
+	if err := app.markAsSkipped(ctx, url, collection, skipReasonRobotsTxt); err != nil {
+		app.Logger.Error(err.Error())
+	}
+	return true
 }
@@ -0,0 +1,148 @@
+package ninjacrawler
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+type productChange int
+
+const (
+	productNew productChange = iota
+	productChanged
+	productUnchanged
+)
+
+// ProductChangeSummary counts how the products of an entity changed since the previous run.
+type ProductChangeSummary struct {
+	New         int32 `json:"new"`
+	Changed     int32 `json:"changed"`
+	Unchanged   int32 `json:"unchanged"`
+	Disappeared int32 `json:"disappeared"`
+}
+
+// productChanges holds the change counts of every entity crawled in this run.
+type productChanges struct {
+	mu       sync.Mutex
+	entities map[string]*ProductChangeSummary
+}
+
+func newProductChanges() *productChanges {
+	return &productChanges{entities: make(map[string]*ProductChangeSummary)}
+}
+
+func (c *productChanges) get(entity string) *ProductChangeSummary {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	summary, ok := c.entities[entity]
+	if !ok {
+		summary = &ProductChangeSummary{}
+		c.entities[entity] = summary
+	}
+	return summary
+}
+
+// productContentHash hashes the extracted content of a product, ignoring the change tracking fields.
+func productContentHash(productDetail *ProductDetail) string {
+	content := *productDetail
+	content.ContentHash = ""
+	content.FirstSeenAt = nil
+	content.LastSeenAt = nil
+	content.LastChangedAt = nil
+	data, _ := json.Marshal(content)
+	return fmt.Sprintf("%x", sha256.Sum256(data))
+}
+
+// trackProductChange compares productDetail with the version stored by a previous run
+// and sets its content hash and first-seen / last-seen / last-changed timestamps.
+func (app *Crawler) trackProductChange(ctx context.Context, entity string, productDetail *ProductDetail) productChange {
+	now := time.Now()
+	productDetail.ContentHash = productContentHash(productDetail)
+	productDetail.LastSeenAt = &now
+
+	change := productNew
+	previous, err := app.store.FindProductDetail(ctx, entity, productDetail.Url)
+	if err == nil && previous != nil {
+		productDetail.FirstSeenAt = previous.FirstSeenAt
+		productDetail.LastChangedAt = previous.LastChangedAt
+		change = productChanged
+		if previous.ContentHash == productDetail.ContentHash {
+			change = productUnchanged
+		}
+	}
+	if productDetail.FirstSeenAt == nil {
+		productDetail.FirstSeenAt = &now
+	}
+	if change != productUnchanged || productDetail.LastChangedAt == nil {
+		productDetail.LastChangedAt = &now
+	}
+
+	// A product crawled twice in one run, e.g. on retry, is only counted once
+	if previous != nil && previous.LastSeenAt != nil && !previous.LastSeenAt.Before(app.StartTime) {
+		return change
+	}
+	summary := app.productChanges.get(entity)
+	switch change {
+	case productNew:
+		atomic.AddInt32(&summary.New, 1)
+	case productChanged:
+		atomic.AddInt32(&summary.Changed, 1)
+	case productUnchanged:
+		atomic.AddInt32(&summary.Unchanged, 1)
+	}
+	return change
+}
+
+// markProductUnchanged bumps the last seen timestamp of the product stored for url, whose page answered 304 Not Modified,
+// and counts it as unchanged. The product is neither extracted nor saved by the crawl otherwise.
+func (app *Crawler) markProductUnchanged(ctx context.Context, entity string, url string) {
+	previous, err := app.store.FindProductDetail(ctx, entity, url)
+	if err != nil || previous == nil {
+		return
+	}
+	seenInRun := previous.LastSeenAt != nil && !previous.LastSeenAt.Before(app.StartTime)
+	now := time.Now()
+	previous.LastSeenAt = &now
+	app.saveProductDetail(ctx, entity, previous)
+	if !seenInRun {
+		atomic.AddInt32(&app.productChanges.get(entity).Unchanged, 1)
+	}
+}
+
+// GetProductChangeSummary returns the change counts of entity for the current run.
+func (app *Crawler) GetProductChangeSummary(entity string) ProductChangeSummary {
+	summary := app.productChanges.get(entity)
+	return ProductChangeSummary{
+		New:         atomic.LoadInt32(&summary.New),
+		Changed:     atomic.LoadInt32(&summary.Changed),
+		Unchanged:   atomic.LoadInt32(&summary.Unchanged),
+		Disappeared: atomic.LoadInt32(&summary.Disappeared),
+	}
+}
+
+// logProductChanges counts the products which were not seen in this run and logs the change summary of entity.
+func (app *Crawler) logProductChanges(entity string) {
+	disappeared := 0
+	for page := 1; ; page++ {
+		products := app.GetProductDetailCollections(entity, page)
+		if len(products) == 0 {
+			break
+		}
+		for _, product := range products {
+			if product.LastSeenAt != nil && product.LastSeenAt.Before(app.StartTime) {
+				disappeared++
+			}
+		}
+	}
+	summary := app.productChanges.get(entity)
+	atomic.StoreInt32(&summary.Disappeared, int32(disappeared))
+
+	changes := app.GetProductChangeSummary(entity)
+	app.Logger.Summary("[%s] New: %d, Changed: %d, Unchanged: %d, Disappeared: %d",
+		entity, changes.New, changes.Changed, changes.Unchanged, changes.Disappeared)
+}
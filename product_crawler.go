@@ -15,9 +15,14 @@ import (
 // Use Crawl instead, which includes improvements for proxy rotation and error handling.
 func (app *Crawler) CrawlPageDetail(processorConfigs []ProcessorConfig) {
 	for _, processorConfig := range processorConfigs {
+		if app.isInterrupted() {
+			break
+		}
 		app.Logger.Summary("Starting :%s: Crawler", processorConfig.OriginCollection)
-		app.overrideEngineDefaults(app.engine, &processorConfig.Engine)
+		app.engine.Store(app.resolveEngine(&processorConfig.Engine))
+		app.warnInsecureEngine(processorConfig.OriginCollection, app.currentEngine())
 		app.toggleClient()
+		app.trackProcessor(processorConfig)
 		processedUrls := make(map[string]bool) // Track processed URLs
 		total := int32(0)
 		app.crawlPageDetailRecursive(processorConfig, processedUrls, &total, 0)
@@ -29,19 +34,24 @@ func (app *Crawler) CrawlPageDetail(processorConfigs []ProcessorConfig) {
 
 		errDataCount := app.GetErrorDataCount(processorConfig.OriginCollection)
 		if errDataCount > 0 {
-			app.Logger.Summary("Error count: %s", errDataCount)
+			app.Logger.Summary("Error count: %d", errDataCount)
 		}
+		app.checkStructure(processorConfig.Entity)
+		app.logProductChanges(processorConfig.Entity)
 		exportProductDetailsToCSV(app, processorConfig.Entity, 1)
 	}
 }
 
 func (app *Crawler) crawlPageDetailRecursive(processorConfig ProcessorConfig, processedUrls map[string]bool, total *int32, counter int32) {
 	for {
-		productListData := app.getUrlCollections(processorConfig.OriginCollection)
+		productListData := app.getUrlCollections(context.Background(), processorConfig.OriginCollection)
 
 		if len(productListData) == 0 {
 			return // Exit recursion if no data to process
 		}
+		if app.structureChanged(processorConfig.Entity) || app.isInterrupted() {
+			return
+		}
 
 		var wg sync.WaitGroup
 
@@ -61,7 +71,7 @@ func (app *Crawler) processProductUrls(productListData []UrlCollection, processo
 func (app *Crawler) handleProductJob(urlCollections []UrlCollection, processorConfig ProcessorConfig, processedUrls map[string]bool, total *int32, counter int32, wg *sync.WaitGroup) {
 	defer wg.Done()
 	// Set a timeout for the context to prevent infinite waiting.
-	timeoutDuration := time.Duration(app.engine.CrawlTimeout) * time.Second
+	timeoutDuration := time.Duration(app.currentEngine().CrawlTimeout) * time.Second
 	ctx, cancel := context.WithTimeout(context.Background(), timeoutDuration)
 	defer cancel()
 
@@ -71,7 +81,7 @@ func (app *Crawler) handleProductJob(urlCollections []UrlCollection, processorCo
 
 	for _, urlCollection := range urlCollections {
 		urlChan <- urlCollection
-		if urlCollection.Attempts > 0 && urlCollection.Attempts <= app.engine.MaxRetryAttempts {
+		if urlCollection.Attempts > 0 && urlCollection.Attempts <= app.currentEngine().MaxRetryAttempts {
 			processedUrls[urlCollection.CurrentPageUrl] = false // Do Not Mark URL as processed
 			processedUrls[urlCollection.Url] = false            // Do Not Mark URL as processed
 		} else {
@@ -81,11 +91,11 @@ func (app *Crawler) handleProductJob(urlCollections []UrlCollection, processorCo
 	}
 	close(urlChan)
 
-	proxyCount := len(app.engine.ProxyServers)
-	batchSize := app.engine.ConcurrentLimit
+	proxyCount := len(app.currentEngine().ProxyServers)
+	batchSize := app.currentEngine().ConcurrentLimit
 	totalUrls := len(urlCollections)
 	goroutineCount := min(max(proxyCount, 1)*batchSize, totalUrls)
-	if app.engine.ProxyStrategy == ProxyStrategyRotation {
+	if app.currentEngine().ProxyStrategy == ProxyStrategyRotation {
 		goroutineCount = min(batchSize, totalUrls)
 	}
 
@@ -93,7 +103,7 @@ func (app *Crawler) handleProductJob(urlCollections []UrlCollection, processorCo
 		proxy := Proxy{}
 		currentProxyIndex := int32(0)
 		if proxyCount > 0 {
-			proxy = app.engine.ProxyServers[i%proxyCount]
+			proxy = app.currentEngine().ProxyServers[i%proxyCount]
 			currentProxyIndex = int32(i % proxyCount)
 		}
 		innerWg.Add(1)
@@ -104,7 +114,6 @@ func (app *Crawler) handleProductJob(urlCollections []UrlCollection, processorCo
 					app.HandlePanic(r)
 				}
 			}()
-			app.CurrentCollection = processorConfig.OriginCollection
 			app.crawlWorker(ctx, processorConfig, urlChan, resultChan, app.isLocalEnv, &counter, &currentProxyIndex)
 		}(proxy)
 	}
@@ -119,14 +128,14 @@ func (app *Crawler) handleProductJob(urlCollections []UrlCollection, processorCo
 		case CrawlResult:
 			switch res := v.Results.(type) {
 			case *ProductDetail:
-				err := app.handleProductDetail(res, processorConfig, v)
+				err := app.handleProductDetail(ctx, res, processorConfig, v)
 				if err != nil {
 					app.Logger.Error(err.Error())
 					continue
 				}
 
 				if !processorConfig.Preference.DoNotMarkAsComplete {
-					err := app.markAsComplete(v.UrlCollection.Url, processorConfig.OriginCollection)
+					err := app.markAsComplete(ctx, v.UrlCollection.Url, processorConfig.OriginCollection)
 					if err != nil {
 						return
 					}
@@ -135,13 +144,14 @@ func (app *Crawler) handleProductJob(urlCollections []UrlCollection, processorCo
 			}
 		}
 	}
-	if app.isLocalEnv && atomic.LoadInt32(&counter) >= int32(app.engine.DevCrawlLimit) {
+	if app.isLocalEnv && atomic.LoadInt32(&counter) >= int32(app.currentEngine().DevCrawlLimit) {
 		cancel()
 	}
 }
 
 // validateRequiredFields checks if the required fields are non-empty in the ProductDetail struct.
-// Returns two slices: one for invalid fields and one for unknown fields.
+// Returns two slices: one for invalid fields and one for unknown fields,
+// and whether a field matched a blacklisted value, which makes the failure retryable.
 /*
 Required Field:
 
@@ -169,9 +179,10 @@ Rule: "FieldName|required|string|max:<length>|trim|blacklists:<value1>,<value2>"
 Example: "SellingPrice|required|string|max:10|trim|blacklists:0,99999"
 
 */
-func validateRequiredFields(product *ProductDetail, validationRules []string) ([]string, []string) {
+func validateRequiredFields(product *ProductDetail, validationRules []string) ([]string, []string, bool) {
 	var invalidFields []string
 	var unknownFields []string
+	blacklisted := false
 
 	v := reflect.ValueOf(*product)
 	t := v.Type()
@@ -223,7 +234,8 @@ func validateRequiredFields(product *ProductDetail, validationRules []string) ([
 				for _, excludeValue := range excludeValues {
 					excludeValue = strings.TrimSpace(excludeValue)
 					if strings.TrimSpace(fieldValueStr) == excludeValue {
-						invalidFields = append(invalidFields, fmt.Sprintf("isRetryable: %s: blacklist value '%s'", f.Name, excludeValue))
+						invalidFields = append(invalidFields, fmt.Sprintf("%s: blacklist value '%s'", f.Name, excludeValue))
+						blacklisted = true
 						break
 					}
 				}
@@ -232,39 +244,48 @@ func validateRequiredFields(product *ProductDetail, validationRules []string) ([
 			}
 		}
 	}
-	return invalidFields, unknownFields
+	return invalidFields, unknownFields, blacklisted
 }
 
-func (app *Crawler) handleProductDetail(res *ProductDetail, processorConfig ProcessorConfig, v CrawlResult) error {
-	invalidFields, unknownFields := validateRequiredFields(res, processorConfig.Preference.ValidationRules)
+func (app *Crawler) handleProductDetail(ctx context.Context, res *ProductDetail, processorConfig ProcessorConfig, v CrawlResult) error {
+	invalidFields, unknownFields, blacklisted := validateRequiredFields(res, processorConfig.Preference.ValidationRules)
 	if len(unknownFields) > 0 {
 		return fmt.Errorf("unknown fields provided: %v", unknownFields)
 	}
 	if len(invalidFields) > 0 {
-		msg := fmt.Sprintf("Validation failed: %v\n", invalidFields)
+		validationErr := &ValidationError{Url: v.UrlCollection.Url, Fields: invalidFields, Retryable: blacklisted}
+		msg := validationErr.Error()
 		html, _ := v.Document.Html()
-		if *app.engine.IsDynamic {
+		if *app.currentEngine().IsDynamic {
 			html, _ = app.GetHtml(v.Page)
 		}
 		app.Logger.Html(html, v.UrlCollection.Url, msg, "validation")
 		var err error
-		if *app.engine.IgnoreRetryOnValidation {
-			err = app.MarkAsMaxErrorAttempt(v.UrlCollection.Url, processorConfig.OriginCollection, msg)
+		if *app.currentEngine().IgnoreRetryOnValidation {
+			err = app.MarkAsMaxErrorAttemptContext(ctx, v.UrlCollection.Url, processorConfig.OriginCollection, msg)
 		} else {
-			err = app.MarkAsError(v.UrlCollection.Url, processorConfig.OriginCollection, msg)
+			err = app.MarkAsErrorContext(ctx, v.UrlCollection.Url, processorConfig.OriginCollection, msg)
 		}
 		if err != nil {
 			return err
 		}
-		return fmt.Errorf(msg)
+		return validationErr
 	}
 
-	app.saveProductDetail(processorConfig.Entity, res)
+	change := app.trackProductChange(ctx, processorConfig.Entity, res)
+	app.saveProductDetail(ctx, processorConfig.Entity, res)
+	if change == productUnchanged {
+		app.Logger.Debug("Product unchanged, skipping submission: %s", res.Url)
+		return nil
+	}
 	if !app.isLocalEnv {
 		err := app.submitProductData(res)
 		if err != nil {
+			// Forget the hash, so the product is submitted again by the next crawl
+			res.ContentHash = ""
+			app.saveProductDetail(ctx, processorConfig.Entity, res)
 			app.Logger.Fatal("Failed to submit product data to API Server: %v", err)
-			err := app.MarkAsError(v.UrlCollection.Url, processorConfig.OriginCollection, err.Error())
+			err := app.MarkAsErrorContext(ctx, v.UrlCollection.Url, processorConfig.OriginCollection, err.Error())
 			if err != nil {
 				return err
 			}
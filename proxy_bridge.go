@@ -0,0 +1,160 @@
+package ninjacrawler
+
+import (
+	"context"
+	"fmt"
+	"golang.org/x/net/proxy"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+)
+
+// proxyBridges runs a local http proxy per authenticated socks5 proxy, for the browsers which cannot authenticate to socks5.
+type proxyBridges struct {
+	bridges map[string]*proxyBridge
+	mu      sync.Mutex
+}
+
+// proxyBridge forwards the requests and CONNECT tunnels it receives on a loopback port through a socks5 proxy.
+type proxyBridge struct {
+	listener  net.Listener
+	server    *http.Server
+	dialer    proxy.ContextDialer
+	transport *http.Transport
+}
+
+func newProxyBridges() *proxyBridges {
+	return &proxyBridges{bridges: make(map[string]*proxyBridge)}
+}
+
+// get returns the url of the bridge to socksUrl, starting it on first use.
+func (b *proxyBridges) get(socksUrl *url.URL) (string, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	key := socksUrl.String()
+	if bridge, ok := b.bridges[key]; ok {
+		return "http://" + bridge.listener.Addr().String(), nil
+	}
+	bridge, err := newProxyBridge(socksUrl)
+	if err != nil {
+		return "", err
+	}
+	b.bridges[key] = bridge
+	return "http://" + bridge.listener.Addr().String(), nil
+}
+
+// close stops every bridge.
+func (b *proxyBridges) close() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for key, bridge := range b.bridges {
+		_ = bridge.server.Close()
+		bridge.transport.CloseIdleConnections()
+		delete(b.bridges, key)
+	}
+}
+
+func newProxyBridge(socksUrl *url.URL) (*proxyBridge, error) {
+	dialer, err := proxy.FromURL(socksUrl, &net.Dialer{Timeout: 30 * time.Second})
+	if err != nil {
+		return nil, err
+	}
+	contextDialer, ok := dialer.(proxy.ContextDialer)
+	if !ok {
+		return nil, fmt.Errorf("socks5 dialer does not support contexts")
+	}
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, err
+	}
+	bridge := &proxyBridge{
+		listener:  listener,
+		dialer:    contextDialer,
+		transport: &http.Transport{Proxy: http.ProxyURL(socksUrl)},
+	}
+	bridge.server = &http.Server{Handler: bridge, ReadHeaderTimeout: 30 * time.Second}
+	go func() {
+		_ = bridge.server.Serve(listener)
+	}()
+	return bridge, nil
+}
+
+func (b *proxyBridge) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodConnect {
+		b.tunnel(w, r)
+		return
+	}
+	outReq := r.Clone(r.Context())
+	outReq.RequestURI = ""
+	outReq.Header.Del("Proxy-Connection")
+	outReq.Header.Del("Proxy-Authorization")
+	resp, err := b.transport.RoundTrip(outReq)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadGateway)
+		return
+	}
+	defer resp.Body.Close()
+	for key, values := range resp.Header {
+		for _, value := range values {
+			w.Header().Add(key, value)
+		}
+	}
+	w.WriteHeader(resp.StatusCode)
+	_, _ = io.Copy(w, resp.Body)
+}
+
+// tunnel connects the browser to the target of a CONNECT request through the socks5 proxy.
+func (b *proxyBridge) tunnel(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), 30*time.Second)
+	defer cancel()
+	upstream, err := b.dialer.DialContext(ctx, "tcp", r.Host)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadGateway)
+		return
+	}
+	hijacker, ok := w.(http.Hijacker)
+	if !ok {
+		_ = upstream.Close()
+		http.Error(w, "hijacking not supported", http.StatusInternalServerError)
+		return
+	}
+	client, buffered, err := hijacker.Hijack()
+	if err != nil {
+		_ = upstream.Close()
+		return
+	}
+	if _, err := client.Write([]byte("HTTP/1.1 200 Connection Established\r\n\r\n")); err != nil {
+		_ = client.Close()
+		_ = upstream.Close()
+		return
+	}
+
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		_, _ = io.Copy(upstream, buffered) // Bytes the browser sent after the CONNECT request are read through buffered
+		closeWrite(upstream)
+	}()
+	go func() {
+		defer wg.Done()
+		_, _ = io.Copy(client, upstream)
+		closeWrite(client)
+	}()
+	wg.Wait()
+	_ = client.Close()
+	_ = upstream.Close()
+}
+
+// closeWrite signals the end of the stream to the peer of conn, closing conn when it cannot half close.
+func closeWrite(conn net.Conn) {
+	if c, ok := conn.(interface{ CloseWrite() error }); ok {
+		_ = c.CloseWrite()
+		return
+	}
+	_ = conn.Close()
+}
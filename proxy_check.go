@@ -0,0 +1,93 @@
+package ninjacrawler
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	defaultProxyCheckUrl = "https://api.ipify.org"
+	proxyCheckTimeout    = 15 * time.Second
+)
+
+// proxyCheck is the outcome of the startup self-test of a proxy.
+type proxyCheck struct {
+	Proxy   Proxy
+	Latency time.Duration
+	Echo    string // Response of the echo url, usually the exit ip of the proxy
+	Err     error
+}
+
+// checkProxies requests the echo url PROXY_CHECK_URL through every proxy and reports the results.
+// The outcome is recorded in the proxy pool, so unreachable proxies start benched. Set SKIP_PROXY_CHECK to skip it.
+func (app *Crawler) checkProxies(ctx context.Context) []proxyCheck {
+	proxies := app.siteEngine.ProxyServers
+	if len(proxies) == 0 || app.Config.GetBool("SKIP_PROXY_CHECK") {
+		return nil
+	}
+	echoUrl := app.Config.EnvString("PROXY_CHECK_URL", defaultProxyCheckUrl)
+
+	results := make([]proxyCheck, len(proxies))
+	var wg sync.WaitGroup
+	for i, proxy := range proxies {
+		wg.Add(1)
+		go func(i int, proxy Proxy) {
+			defer wg.Done()
+			results[i] = app.checkProxy(ctx, echoUrl, proxy)
+		}(i, proxy)
+	}
+	wg.Wait()
+
+	reachable := 0
+	for _, result := range results {
+		app.proxyPool.record(result.Proxy, result.Latency, result.Err)
+		if result.Err != nil {
+			app.Logger.Warn("Proxy check %s failed: %v", result.Proxy.Server, result.Err)
+			continue
+		}
+		reachable++
+		app.Logger.Info("Proxy check %s: %dms, echo %s", result.Proxy.Server, result.Latency.Milliseconds(), result.Echo)
+	}
+	app.Logger.Summary("Proxy check: %d of %d proxies reachable through %s", reachable, len(proxies), echoUrl)
+	return results
+}
+
+// checkProxy requests echoUrl through the pooled transport of proxy, so the crawl starts with a warm connection.
+func (app *Crawler) checkProxy(ctx context.Context, echoUrl string, proxy Proxy) proxyCheck {
+	result := proxyCheck{Proxy: proxy}
+	transport, err := app.transports.get(proxy, app.siteEngine)
+	if err != nil {
+		result.Err = err
+		return result
+	}
+	client := &http.Client{Transport: transport, Timeout: proxyCheckTimeout}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, echoUrl, nil)
+	if err != nil {
+		result.Err = err
+		return result
+	}
+	start := time.Now()
+	resp, err := client.Do(req)
+	result.Latency = time.Since(start)
+	if err != nil {
+		result.Err = &ProxyError{Proxy: proxy, Err: err}
+		return result
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		result.Err = &ProxyError{Proxy: proxy, Err: &HTTPStatusError{Url: echoUrl, StatusCode: resp.StatusCode, Status: resp.Status}}
+		return result
+	}
+	echo, err := io.ReadAll(io.LimitReader(resp.Body, 256))
+	if err != nil {
+		result.Err = &ProxyError{Proxy: proxy, Err: err}
+		return result
+	}
+	result.Echo = strings.TrimSpace(string(echo))
+	return result
+}
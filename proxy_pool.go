@@ -0,0 +1,283 @@
+package ninjacrawler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	proxyBaseCooldown = 30 * time.Second // Bench time after the first failure, doubled for every further failure in a row
+	proxyMaxCooldown  = 10 * time.Minute
+	proxyRecentBlocks = 5 // Number of block status codes kept per proxy
+)
+
+// ProxyPool hands out the proxies of a site and tracks their health.
+// A proxy failing with a block, a proxy error or a timeout is benched with an exponential cooldown,
+// and proxies without recent failures are preferred.
+type ProxyPool struct {
+	proxies  []Proxy
+	health   map[string]*proxyHealth
+	sessions map[string]string // Sticky session key to the server of its proxy
+	mu       sync.Mutex
+	current  int
+}
+
+// proxyHealth is the record of the requests sent through a proxy during the run.
+type proxyHealth struct {
+	requests            int
+	successes           int
+	failures            int
+	totalLatency        time.Duration
+	consecutiveFailures int
+	recentBlocks        []int
+	benchedUntil        time.Time
+}
+
+// ProxyStats is the health of a proxy, reported in the run summary.
+type ProxyStats struct {
+	Server       string     `json:"server" bson:"server"`
+	Requests     int        `json:"requests" bson:"requests"`
+	Successes    int        `json:"successes" bson:"successes"`
+	Failures     int        `json:"failures" bson:"failures"`
+	SuccessRate  float64    `json:"success_rate" bson:"success_rate"`
+	AvgLatencyMs int64      `json:"avg_latency_ms" bson:"avg_latency_ms"`
+	RecentBlocks []int      `json:"recent_blocks,omitempty" bson:"recent_blocks,omitempty"`
+	BenchedUntil *time.Time `json:"benched_until,omitempty" bson:"benched_until,omitempty"`
+}
+
+func newProxyPool(proxies []Proxy) *ProxyPool {
+	return &ProxyPool{
+		proxies:  proxies,
+		health:   make(map[string]*proxyHealth),
+		sessions: make(map[string]string),
+		current:  0,
+	}
+}
+
+// setProxies replaces the proxies handed out, the health of known proxies is kept.
+// Sessions bound to a removed proxy are dropped.
+func (p *ProxyPool) setProxies(proxies []Proxy) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.proxies = proxies
+	p.current = 0
+	for key, server := range p.sessions {
+		if p.indexOf(server) == -1 {
+			delete(p.sessions, key)
+		}
+	}
+}
+
+// getNext returns the next proxy in round-robin order, preferring proxies without recent failures and skipping benched ones.
+// When every proxy is benched, the one whose cooldown ends first is returned.
+func (p *ProxyPool) getNext() Proxy {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.next()
+}
+
+// getSticky returns the proxy bound to the session key, binding the next proxy when the session has none.
+// A session keeps its proxy until the proxy fails.
+func (p *ProxyPool) getSticky(key string) Proxy {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if server, ok := p.sessions[key]; ok {
+		if index := p.indexOf(server); index != -1 {
+			return p.proxies[index]
+		}
+	}
+	proxy := p.next()
+	if proxy.Server != "" {
+		p.sessions[key] = proxy.Server
+	}
+	return proxy
+}
+
+func (p *ProxyPool) next() Proxy {
+	if len(p.proxies) == 0 {
+		return Proxy{}
+	}
+
+	now := time.Now()
+	best, bestRank := -1, 0
+	for i := 0; i < len(p.proxies); i++ {
+		index := (p.current + i) % len(p.proxies)
+		rank := p.rank(p.proxies[index], now)
+		if best == -1 || rank < bestRank {
+			best, bestRank = index, rank
+		}
+		if rank == 0 {
+			break
+		}
+	}
+	if bestRank == 2 {
+		for index, proxy := range p.proxies {
+			if p.health[proxy.Server].benchedUntil.Before(p.health[p.proxies[best].Server].benchedUntil) {
+				best = index
+			}
+		}
+	}
+
+	p.current = (best + 1) % len(p.proxies)
+	return p.proxies[best]
+}
+
+// rank orders proxies by preference: 0 healthy, 1 recovering from a failure, 2 benched.
+func (p *ProxyPool) rank(proxy Proxy, now time.Time) int {
+	health, ok := p.health[proxy.Server]
+	switch {
+	case !ok || health.consecutiveFailures == 0:
+		return 0
+	case now.Before(health.benchedUntil):
+		return 2
+	default:
+		return 1
+	}
+}
+
+// isBenched reports whether proxy is cooling down after a failure.
+func (p *ProxyPool) isBenched(proxy Proxy) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	health, ok := p.health[proxy.Server]
+	return ok && time.Now().Before(health.benchedUntil)
+}
+
+// record updates the health of proxy with the outcome of a request.
+func (p *ProxyPool) record(proxy Proxy, latency time.Duration, err error) {
+	var skippedErr *SkippedError
+	if proxy.Server == "" || errors.Is(err, context.Canceled) || errors.As(err, &skippedErr) {
+		return
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	health, ok := p.health[proxy.Server]
+	if !ok {
+		health = &proxyHealth{}
+		p.health[proxy.Server] = health
+	}
+	health.requests++
+	health.totalLatency += latency
+
+	if !isProxyFault(err) {
+		health.successes++
+		health.consecutiveFailures = 0
+		health.benchedUntil = time.Time{}
+		return
+	}
+
+	health.failures++
+	health.consecutiveFailures++
+	var statusErr *HTTPStatusError
+	if errors.As(err, &statusErr) {
+		health.recentBlocks = append(health.recentBlocks, statusErr.StatusCode)
+		if len(health.recentBlocks) > proxyRecentBlocks {
+			health.recentBlocks = health.recentBlocks[len(health.recentBlocks)-proxyRecentBlocks:]
+		}
+	}
+	health.benchedUntil = time.Now().Add(proxyCooldown(health.consecutiveFailures))
+	for key, server := range p.sessions {
+		if server == proxy.Server {
+			delete(p.sessions, key)
+		}
+	}
+}
+
+// indexOf returns the position of the proxy with the given server, -1 when it is not handed out.
+func (p *ProxyPool) indexOf(server string) int {
+	for index, proxy := range p.proxies {
+		if proxy.Server == server {
+			return index
+		}
+	}
+	return -1
+}
+
+// stats returns the health of every proxy used during the run, sorted by server.
+func (p *ProxyPool) stats() []ProxyStats {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	now := time.Now()
+	stats := make([]ProxyStats, 0, len(p.health))
+	for server, health := range p.health {
+		stat := ProxyStats{
+			Server:       server,
+			Requests:     health.requests,
+			Successes:    health.successes,
+			Failures:     health.failures,
+			RecentBlocks: append([]int(nil), health.recentBlocks...),
+		}
+		if health.requests > 0 {
+			stat.SuccessRate = float64(health.successes) / float64(health.requests)
+			stat.AvgLatencyMs = (health.totalLatency / time.Duration(health.requests)).Milliseconds()
+		}
+		if now.Before(health.benchedUntil) {
+			benchedUntil := health.benchedUntil
+			stat.BenchedUntil = &benchedUntil
+		}
+		stats = append(stats, stat)
+	}
+	sort.Slice(stats, func(i, j int) bool {
+		return stats[i].Server < stats[j].Server
+	})
+	return stats
+}
+
+// proxyCooldown returns the bench time after the given number of failures in a row.
+func proxyCooldown(consecutiveFailures int) time.Duration {
+	cooldown := proxyBaseCooldown
+	for i := 1; i < consecutiveFailures && cooldown < proxyMaxCooldown; i++ {
+		cooldown *= 2
+	}
+	if cooldown > proxyMaxCooldown {
+		return proxyMaxCooldown
+	}
+	return cooldown
+}
+
+// isProxyFault reports whether err counts against the proxy: blocks, proxy failures, timeouts and network errors.
+// Any other status code proves the proxy works.
+func isProxyFault(err error) bool {
+	var blockedErr *BlockedError
+	var proxyErr *ProxyError
+	var timeoutErr *TimeoutError
+	var netErr net.Error
+	switch {
+	case err == nil:
+		return false
+	case errors.As(err, &blockedErr), errors.As(err, &proxyErr), errors.As(err, &timeoutErr), errors.As(err, &netErr):
+		return true
+	}
+	return isProxyFailure(err)
+}
+
+// logProxyStats logs the health of every proxy used during the run.
+func (app *Crawler) logProxyStats(stats []ProxyStats) {
+	for _, stat := range stats {
+		status := "healthy"
+		if stat.BenchedUntil != nil {
+			status = fmt.Sprintf("benched until %s", stat.BenchedUntil.Format(time.TimeOnly))
+		}
+		app.Logger.Summary("Proxy %s: %d requests, %.0f%% success, avg latency %dms, recent blocks %v, %s",
+			stat.Server, stat.Requests, stat.SuccessRate*100, stat.AvgLatencyMs, stat.RecentBlocks, status)
+	}
+}
+
+// indexOfProxy returns the position of proxy in proxies, 0 when it is not found.
+func indexOfProxy(proxies []Proxy, proxy Proxy) int {
+	for index, candidate := range proxies {
+		if candidate.Server == proxy.Server {
+			return index
+		}
+	}
+	return 0
+}
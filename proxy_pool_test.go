@@ -0,0 +1,167 @@
+package ninjacrawler
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestProxyCooldown(t *testing.T) {
+	tests := []struct {
+		failures int
+		want     time.Duration
+	}{
+		{1, 30 * time.Second},
+		{2, time.Minute},
+		{3, 2 * time.Minute},
+		{5, 8 * time.Minute},
+		{6, 10 * time.Minute},
+		{50, 10 * time.Minute},
+	}
+	for _, test := range tests {
+		if got := proxyCooldown(test.failures); got != test.want {
+			t.Errorf("proxyCooldown(%d) = %v, want %v", test.failures, got, test.want)
+		}
+	}
+}
+
+func TestProxyPoolRecord(t *testing.T) {
+	blocked := &BlockedError{Url: "http://example.test", Err: &HTTPStatusError{StatusCode: http.StatusForbidden}}
+	tests := []struct {
+		name     string
+		errs     []error
+		benched  bool
+		cooldown time.Duration
+		blocks   []int
+	}{
+		{"success", []error{nil}, false, 0, nil},
+		{"not found proves the proxy works", []error{&HTTPStatusError{StatusCode: http.StatusNotFound}}, false, 0, nil},
+		{"block", []error{blocked}, true, 30 * time.Second, []int{403}},
+		{"failures in a row double the cooldown", []error{blocked, &ProxyError{Err: errors.New("refused")}, blocked}, true, 2 * time.Minute, []int{403, 403}},
+		{"success clears the cooldown", []error{blocked, blocked, nil}, false, 0, []int{403, 403}},
+		{"canceled requests are not counted", []error{context.Canceled}, false, 0, nil},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			proxy := Proxy{Server: "http://10.0.0.1:3128"}
+			pool := newProxyPool([]Proxy{proxy})
+			for _, err := range test.errs {
+				pool.record(proxy, time.Millisecond, err)
+			}
+			if pool.isBenched(proxy) != test.benched {
+				t.Fatalf("benched = %v, want %v", !test.benched, test.benched)
+			}
+			if test.benched {
+				remaining := time.Until(pool.health[proxy.Server].benchedUntil)
+				if remaining > test.cooldown || remaining < test.cooldown-time.Second {
+					t.Errorf("cooldown = %v, want %v", remaining, test.cooldown)
+				}
+			}
+			var blocks []int
+			if stats := pool.stats(); len(stats) > 0 {
+				blocks = stats[0].RecentBlocks
+			}
+			if !slices.Equal(blocks, test.blocks) {
+				t.Errorf("recent blocks = %v, want %v", blocks, test.blocks)
+			}
+		})
+	}
+}
+
+func TestProxyPoolNext(t *testing.T) {
+	proxies := []Proxy{{Server: "http://a"}, {Server: "http://b"}, {Server: "http://c"}}
+	blocked := &BlockedError{Err: &HTTPStatusError{StatusCode: http.StatusForbidden}}
+	tests := []struct {
+		name  string
+		setup func(pool *ProxyPool)
+		want  []string
+	}{
+		{
+			name:  "round robin",
+			setup: func(*ProxyPool) {},
+			want:  []string{"http://a", "http://b", "http://c", "http://a"},
+		},
+		{
+			name: "benched proxy is skipped",
+			setup: func(pool *ProxyPool) {
+				pool.record(proxies[1], 0, blocked)
+			},
+			want: []string{"http://a", "http://c", "http://a", "http://c"},
+		},
+		{
+			name: "healthy proxy preferred over a recovering one",
+			setup: func(pool *ProxyPool) {
+				pool.record(proxies[0], 0, blocked)
+				pool.health[proxies[0].Server].benchedUntil = time.Now().Add(-time.Second)
+			},
+			want: []string{"http://b", "http://c", "http://b"},
+		},
+		{
+			name: "recovering proxy preferred over a benched one",
+			setup: func(pool *ProxyPool) {
+				for _, proxy := range proxies {
+					pool.record(proxy, 0, blocked)
+				}
+				pool.health[proxies[2].Server].benchedUntil = time.Now().Add(-time.Second)
+			},
+			want: []string{"http://c", "http://c"},
+		},
+		{
+			name: "every proxy benched, the first one back is used",
+			setup: func(pool *ProxyPool) {
+				for _, proxy := range proxies {
+					pool.record(proxy, 0, blocked)
+				}
+				pool.record(proxies[0], 0, blocked)
+				pool.record(proxies[2], 0, blocked)
+			},
+			want: []string{"http://b", "http://b"},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			pool := newProxyPool(proxies)
+			test.setup(pool)
+			for i, want := range test.want {
+				if got := pool.getNext().Server; got != want {
+					t.Errorf("proxy %d = %s, want %s", i, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestProxyPoolStickySessions(t *testing.T) {
+	proxies := []Proxy{{Server: "http://a"}, {Server: "http://b"}}
+	pool := newProxyPool(proxies)
+
+	first := pool.getSticky("cart")
+	if got := pool.getSticky("cart"); got.Server != first.Server {
+		t.Fatalf("session moved from %s to %s", first.Server, got.Server)
+	}
+	if other := pool.getSticky("other"); other.Server == first.Server {
+		t.Errorf("second session bound to %s, want the next proxy", other.Server)
+	}
+
+	// A proxy failure evicts the sessions bound to it, the session moves to the next healthy proxy
+	pool.record(first, 0, &ProxyError{Proxy: first, Err: errors.New("refused")})
+	if _, ok := pool.sessions["cart"]; ok {
+		t.Error("session of the failed proxy was kept")
+	}
+	moved := pool.getSticky("cart")
+	if moved.Server != proxies[1].Server {
+		t.Errorf("session bound to %s after the failure of %s, want %s", moved.Server, first.Server, proxies[1].Server)
+	}
+	if got := pool.getSticky("cart"); got.Server != moved.Server {
+		t.Errorf("session moved from %s to %s without a failure", moved.Server, got.Server)
+	}
+
+	// Sessions bound to a proxy removed from the list are dropped
+	pool.setProxies(proxies[:1])
+	if _, ok := pool.sessions["cart"]; ok {
+		t.Error("session of the removed proxy was kept")
+	}
+}
@@ -0,0 +1,52 @@
+package ninjacrawler
+
+import "context"
+
+// proxySessionKey returns the sticky proxy session of urlCollection: its SessionKey, else its parent url.
+// Urls without a parent are their own session.
+func proxySessionKey(urlCollection UrlCollection) string {
+	if urlCollection.SessionKey != "" {
+		return urlCollection.SessionKey
+	}
+	if urlCollection.Parent != "" {
+		return urlCollection.Parent
+	}
+	return urlCollection.Url
+}
+
+// isStickyProxy reports whether the requests of engine keep the proxy of their session.
+// Dynamic engines are sticky too, the browser pool keeps a browser per proxy.
+func isStickyProxy(engine *Engine) bool {
+	return *engine.StickyProxy && len(engine.ProxyServers) > 0
+}
+
+// sessionProxy returns the proxy bound to the session of urlCollection with StickyProxy, proxy otherwise.
+func (app *Crawler) sessionProxy(ctx context.Context, urlCollection UrlCollection, proxy Proxy) Proxy {
+	if !isStickyProxy(app.engineFor(ctx)) {
+		return proxy
+	}
+	return app.proxyPool.getSticky(proxySessionKey(urlCollection))
+}
+
+// withProxySession returns a copy of ctx carrying the sticky proxy session of urlCollection,
+// so Navigate and Navigates called from its handler go through the same proxy.
+func (app *Crawler) withProxySession(ctx context.Context, urlCollection UrlCollection) context.Context {
+	if !isStickyProxy(app.engineFor(ctx)) {
+		return ctx
+	}
+	return withCrawlRequest(ctx, &crawlRequest{Engine: crawlRequestFrom(ctx).Engine, Session: proxySessionKey(urlCollection)})
+}
+
+// inheritSessionKey passes the SessionKey chosen for parent on to the collections found on it which have none,
+// so the whole crawl path below parent shares one proxy.
+func inheritSessionKey(collections []UrlCollection, parent UrlCollection) []UrlCollection {
+	if parent.SessionKey == "" {
+		return collections
+	}
+	for i := range collections {
+		if collections[i].SessionKey == "" {
+			collections[i].SessionKey = parent.SessionKey
+		}
+	}
+	return collections
+}
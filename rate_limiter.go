@@ -0,0 +1,94 @@
+package ninjacrawler
+
+import (
+	"context"
+	"net/url"
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+// RateLimit is a token bucket shared by all requests to the same host.
+type RateLimit struct {
+	RequestsPerSecond float64 // Steady request rate per host
+	Burst             int     // Requests allowed at once before the rate applies, defaults to 1
+}
+
+// hostLimiter keeps one token bucket per target host.
+type hostLimiter struct {
+	mu          sync.Mutex
+	limiters    map[string]*rate.Limiter
+	crawlDelays map[string]time.Duration
+}
+
+func newHostLimiter() *hostLimiter {
+	return &hostLimiter{
+		limiters:    make(map[string]*rate.Limiter),
+		crawlDelays: make(map[string]time.Duration),
+	}
+}
+
+// setCrawlDelay sets the minimum interval between requests to host, as requested by its robots.txt.
+func (h *hostLimiter) setCrawlDelay(host string, delay time.Duration) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.crawlDelays[host] = delay
+}
+
+// limitFor returns the effective limit of host: the engine limit, slowed down to the Crawl-delay of the host.
+// ok is false when requests to host are unlimited.
+func (h *hostLimiter) limitFor(host string, limit *RateLimit) (RateLimit, bool) {
+	h.mu.Lock()
+	delay := h.crawlDelays[host]
+	h.mu.Unlock()
+
+	var effective RateLimit
+	if limit != nil && limit.RequestsPerSecond > 0 {
+		effective = *limit
+	}
+	if delay > 0 {
+		delayRate := float64(time.Second) / float64(delay)
+		if effective.RequestsPerSecond <= 0 || delayRate < effective.RequestsPerSecond {
+			effective = RateLimit{RequestsPerSecond: delayRate, Burst: 1}
+		}
+	}
+	return effective, effective.RequestsPerSecond > 0
+}
+
+// get returns the limiter of host, updating it when the processor engine changed the limit.
+func (h *hostLimiter) get(host string, limit RateLimit) *rate.Limiter {
+	burst := limit.Burst
+	if burst < 1 {
+		burst = 1
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	limiter, ok := h.limiters[host]
+	if !ok {
+		limiter = rate.NewLimiter(rate.Limit(limit.RequestsPerSecond), burst)
+		h.limiters[host] = limiter
+		return limiter
+	}
+	if limiter.Limit() != rate.Limit(limit.RequestsPerSecond) {
+		limiter.SetLimit(rate.Limit(limit.RequestsPerSecond))
+	}
+	if limiter.Burst() != burst {
+		limiter.SetBurst(burst)
+	}
+	return limiter
+}
+
+// waitForRateLimit blocks until a request to the host of rawUrl is allowed by Engine.RateLimit and the Crawl-delay of the host.
+func (app *Crawler) waitForRateLimit(ctx context.Context, rawUrl string) error {
+	parsed, err := url.Parse(rawUrl)
+	if err != nil {
+		return nil
+	}
+	limit, ok := app.rateLimiter.limitFor(parsed.Hostname(), app.engineFor(ctx).RateLimit)
+	if !ok {
+		return nil
+	}
+	return app.rateLimiter.get(parsed.Hostname(), limit).Wait(ctx)
+}
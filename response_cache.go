@@ -0,0 +1,95 @@
+package ninjacrawler
+
+import (
+	"context"
+	"crypto/sha1"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// cachedResponse is a response body stored by the development response cache.
+type cachedResponse struct {
+	Url         string    `json:"url"`
+	ContentType string    `json:"content_type"`
+	Body        []byte    `json:"body"`
+	CachedAt    time.Time `json:"cached_at"`
+}
+
+// useResponseCache reports whether responses are served from disk. It is only honored in the local environment.
+func (app *Crawler) useResponseCache(ctx context.Context) bool {
+	engine := app.engineFor(ctx)
+	return app.isLocalEnv && engine.ResponseCache != nil && *engine.ResponseCache
+}
+
+func (app *Crawler) responseCachePath(url string) string {
+	return filepath.Join("storage", "cache", app.Name, fmt.Sprintf("%x.json", sha1.Sum([]byte(url))))
+}
+
+func (app *Crawler) readResponseCache(url string) (*cachedResponse, bool) {
+	data, err := os.ReadFile(app.responseCachePath(url))
+	if err != nil {
+		return nil, false
+	}
+	var cached cachedResponse
+	if err := json.Unmarshal(data, &cached); err != nil {
+		return nil, false
+	}
+	return &cached, true
+}
+
+func (app *Crawler) writeResponseCache(url string, contentType string, body []byte) error {
+	path := app.responseCachePath(url)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	data, err := json.Marshal(cachedResponse{Url: url, ContentType: contentType, Body: body, CachedAt: time.Now()})
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0644)
+}
+
+// setConditionalHeaders adds If-None-Match and If-Modified-Since from the validators stored on the url collection being crawled.
+// Urls which failed before are always downloaded again.
+func (app *Crawler) setConditionalHeaders(ctx context.Context, header http.Header, url string) {
+	engine := app.engineFor(ctx)
+	if engine.ConditionalRequests == nil || !*engine.ConditionalRequests {
+		return
+	}
+	navigation := crawlRequestFrom(ctx)
+	urlCollection, err := app.store.FindUrlCollection(ctx, navigation.Collection, navigation.documentUrl(url))
+	if err != nil || urlCollection.Error {
+		return
+	}
+	if urlCollection.ETag != "" {
+		header.Set("If-None-Match", urlCollection.ETag)
+	}
+	if urlCollection.LastModified != "" {
+		header.Set("If-Modified-Since", urlCollection.LastModified)
+	}
+}
+
+// updateValidators stores the ETag and Last-Modified of a response on the url collection being crawled, for the next conditional request.
+func (app *Crawler) updateValidators(ctx context.Context, url string, header http.Header) {
+	engine := app.engineFor(ctx)
+	if engine.ConditionalRequests == nil || !*engine.ConditionalRequests {
+		return
+	}
+	etag := header.Get("ETag")
+	lastModified := header.Get("Last-Modified")
+	if etag == "" && lastModified == "" {
+		return
+	}
+	navigation := crawlRequestFrom(ctx)
+	err := app.store.UpdateUrlCollection(ctx, navigation.Collection, navigation.documentUrl(url), Map{
+		"etag":          etag,
+		"last_modified": lastModified,
+	})
+	if err != nil {
+		app.Logger.Error("Could not store validators of %s: %v", url, err)
+	}
+}
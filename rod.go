@@ -1,22 +1,36 @@
 package ninjacrawler
 
 import (
+	"context"
 	"fmt"
 	"github.com/PuerkitoBio/goquery"
 	"github.com/go-rod/rod"
 	"github.com/go-rod/rod/lib/launcher"
 	"github.com/go-rod/rod/lib/launcher/flags"
 	"github.com/go-rod/rod/lib/proto"
+	"go.opentelemetry.io/otel/attribute"
+	semconv "go.opentelemetry.io/otel/semconv/v1.24.0"
 )
 
 // GetRodBrowser initializes and runs Rod browser.
 // It returns a Rod browser instance if successful, otherwise returns an error.
 func (app *Crawler) GetRodBrowser(proxy Proxy) (*rod.Browser, error) {
+	return app.launchRodBrowser(app.currentEngine(), proxy)
+}
+
+// launchRodBrowser runs a Rod browser of engine through proxy.
+func (app *Crawler) launchRodBrowser(engine *Engine, proxy Proxy) (*rod.Browser, error) {
 	// Setup the browser launcher with proxy if provided
 	l := launcher.New().Headless(!app.isLocalEnv).Devtools(app.isLocalEnv).NoSandbox(!app.isLocalEnv)
 
-	if len(app.engine.ProxyServers) > 0 && proxy.Server != "" {
-		l = l.Set(flags.ProxyServer, proxy.Server)
+	var username, password string
+	if len(engine.ProxyServers) > 0 && proxy.Server != "" {
+		server, proxyUsername, proxyPassword, err := app.browserProxy(proxy)
+		if err != nil {
+			return nil, err
+		}
+		username, password = proxyUsername, proxyPassword
+		l = l.Set(flags.ProxyServer, server)
 	}
 	//fmt.Println(l.FormatArgs())
 	url, err := l.Launch()
@@ -25,9 +39,9 @@ func (app *Crawler) GetRodBrowser(proxy Proxy) (*rod.Browser, error) {
 	}
 	browser := rod.New().ControlURL(url).MustConnect()
 	// Optionally handle proxy authentication
-	if proxy.Username != "" && proxy.Password != "" {
+	if username != "" && password != "" {
 		go func() {
-			errAuth := browser.HandleAuth(proxy.Username, proxy.Password)()
+			errAuth := browser.HandleAuth(username, password)()
 			if errAuth != nil {
 				app.Logger.Error("Error handling proxy authentication: %s", errAuth.Error())
 			}
@@ -58,28 +72,64 @@ func (app *Crawler) GetRodPage(browser *rod.Browser) (*rod.Page, error) {
 // NavigateRodURL navigates to a specified URL using the Rod page.
 // It waits until the page is fully loaded, handles cookie consent, and returns the page DOM.
 func (app *Crawler) NavigateRodURL(pageInterFace interface{}, url string, proxy Proxy) (*rod.Page, *goquery.Document, error) {
+	return app.navigateRodURL(context.Background(), pageInterFace, url, proxy)
+}
+
+// rodFetcher is the "rod" provider, navigating the Rod page of the request.
+// Unsuccessful status codes are returned as errors.
+type rodFetcher struct {
+	app *Crawler
+}
+
+func (f *rodFetcher) Fetch(ctx context.Context, req *FetchRequest) (*FetchResponse, error) {
+	page, document, err := f.app.navigateRodURL(ctx, req.Page, req.Url, req.Proxy)
+	if err != nil {
+		return nil, err
+	}
+	finalUrl := ""
+	if info, infoErr := page.Info(); infoErr == nil {
+		finalUrl = info.URL
+	}
+	return browserResponse(page, finalUrl, document), nil
+}
+
+func (app *Crawler) navigateRodURL(ctx context.Context, pageInterFace interface{}, url string, proxy Proxy) (*rod.Page, *goquery.Document, error) {
+	engine := app.engineFor(ctx)
 	var page *rod.Page
 	page = pageInterFace.(*rod.Page)
 	e := proto.NetworkResponseReceived{}
 	wait := page.WaitEvent(&e)
 	// Go to the URL with a timeout
-	pageWithTimeout := page.Timeout(app.engine.Timeout)
+	pageWithTimeout := page.Context(ctx).Timeout(engine.Timeout)
+	if err := app.waitForRateLimit(ctx, url); err != nil {
+		return nil, nil, err
+	}
+	_, gotoSpan := app.startSpan(ctx, "goto", semconv.URLFull(url))
 	err := pageWithTimeout.Navigate(url)
 	if err != nil {
-		d, e := app.handleProxyError(proxy, err)
+		d, e := app.handleProxyError(ctx, proxy, err)
+		endSpan(gotoSpan, e)
 		return nil, d, e
 	}
 	wait()
 	if e.Response == nil {
-		return nil, nil, fmt.Errorf("no response received: %+v", e)
+		responseErr := fmt.Errorf("no response received: %+v", e)
+		endSpan(gotoSpan, responseErr)
+		return nil, nil, responseErr
 	}
+	gotoSpan.SetAttributes(semconv.HTTPResponseStatusCode(e.Response.Status))
 	if !Ok(e.Response.Status) {
-		return nil, nil, app.handleHttpError(e.Response.Status, e.Response.StatusText, url, page)
+		httpErr := app.handleHttpError(ctx, e.Response.Status, e.Response.StatusText, url, page)
+		endSpan(gotoSpan, httpErr)
+		return nil, nil, httpErr
 	}
+	gotoSpan.End()
 
 	// Wait for selector if applicable
-	if app.engine.WaitForSelector != nil {
-		elm, navErr := page.Timeout(app.engine.Timeout).Element(*app.engine.WaitForSelector)
+	if engine.WaitForSelector != nil {
+		_, waitSpan := app.startSpan(ctx, "wait_for_selector", attribute.String("selector", *engine.WaitForSelector))
+		elm, navErr := page.Timeout(engine.Timeout).Element(*engine.WaitForSelector)
+		endSpan(waitSpan, navErr)
 		if navErr != nil {
 			msg := fmt.Sprintf("element not found: %s", navErr.Error())
 			html, htmlErr := page.HTML()
@@ -91,7 +141,7 @@ func (app *Crawler) NavigateRodURL(pageInterFace interface{}, url string, proxy
 			return nil, nil, fmt.Errorf("element not found: %s", navErr.Error())
 		}
 		if elm == nil {
-			err = page.WaitStable(app.engine.Timeout)
+			err = page.WaitStable(engine.Timeout)
 			if err != nil {
 				return nil, nil, fmt.Errorf("page did not stabilize: %w", err)
 			}
@@ -104,7 +154,7 @@ func (app *Crawler) NavigateRodURL(pageInterFace interface{}, url string, proxy
 	}
 
 	// Handle cookie consent
-	if err = handleCookieConsent(page, app.engine.CookieConsent); err != nil {
+	if err = handleCookieConsent(page, engine.CookieConsent); err != nil {
 		html, _ := app.GetHtml(page)
 		app.Logger.Html(html, url, err.Error())
 		return nil, nil, err
@@ -117,13 +167,13 @@ func (app *Crawler) NavigateRodURL(pageInterFace interface{}, url string, proxy
 	}
 
 	// Optionally send HTML to BigQuery or store it
-	if app.engine.SendHtmlToBigquery != nil && *app.engine.SendHtmlToBigquery {
-		sendErr := app.SendHtmlToBigquery(document, url)
+	if engine.SendHtmlToBigquery != nil && *engine.SendHtmlToBigquery {
+		sendErr := app.SendHtmlToBigqueryContext(ctx, document, url)
 		if sendErr != nil {
 			app.Logger.Error("SendHtmlToBigquery Error: %s", sendErr.Error())
 		}
 	}
-	if *app.engine.StoreHtml {
+	if *engine.StoreHtml {
 		if err := app.SaveHtml(document, url); err != nil {
 			app.Logger.Error(err.Error())
 		}
@@ -2,6 +2,7 @@ package ninjacrawler
 
 import (
 	"github.com/PuerkitoBio/goquery"
+	semconv "go.opentelemetry.io/otel/semconv/v1.24.0"
 	"reflect"
 	"regexp"
 	"strconv"
@@ -48,8 +49,11 @@ func parseKeys(path string) []string {
 func (ctx *CrawlerContext) scrapData(processor interface{}) *ProductDetail {
 	app := ctx.App
 	document := ctx.Document
+	_, span := app.startSpan(ctx.Context(), "scrape", semconv.URLFull(ctx.UrlCollection.Url))
+	defer span.End()
 	productDetail := &ProductDetail{}
 	productDetailSelector := reflect.ValueOf(processor)
+	fillRates := make(map[string]float64)
 
 	for i := 0; i < productDetailSelector.NumField(); i++ {
 		fieldValue := productDetailSelector.Field(i)
@@ -59,8 +63,10 @@ func (ctx *CrawlerContext) scrapData(processor interface{}) *ProductDetail {
 		switch v := fieldValue.Interface().(type) {
 		case string:
 			reflect.ValueOf(productDetail).Elem().FieldByName(fieldName).SetString(v)
+			continue
 		case []string:
 			reflect.ValueOf(productDetail).Elem().FieldByName(fieldName).Set(reflect.ValueOf(v))
+			continue
 		case func(CrawlerContext) []AttributeItem:
 			result := fieldValue.Interface().(func(CrawlerContext) []AttributeItem)(*ctx)
 			reflect.ValueOf(productDetail).Elem().FieldByName(fieldName).Set(reflect.ValueOf(result))
@@ -86,8 +92,16 @@ func (ctx *CrawlerContext) scrapData(processor interface{}) *ProductDetail {
 			reflect.ValueOf(productDetail).Elem().FieldByName(fieldName).Set(reflect.ValueOf(stringSlice))
 		default:
 			app.Logger.Error("Invalid %s CrawlerContext: %T", fieldName, v)
+			continue
+		}
+
+		// Constant values are not selectors, only extracted fields count towards the fill rate
+		fillRates["field:"+fieldName] = 0
+		if !reflect.ValueOf(productDetail).Elem().FieldByName(fieldName).IsZero() {
+			fillRates["field:"+fieldName] = 1
 		}
 	}
+	app.observeStructure(fillRates)
 
 	return productDetail
 }
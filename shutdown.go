@@ -0,0 +1,126 @@
+package ninjacrawler
+
+import (
+	"context"
+	"errors"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+)
+
+// defaultShutdownGracePeriod fits in the default terminationGracePeriodSeconds (30s) of Kubernetes,
+// leaving time for Stop to upload logs and raw html.
+const defaultShutdownGracePeriod = 25 * time.Second
+
+// ErrInterrupted stops a processor once the crawler received a termination signal.
+var ErrInterrupted = errors.New("crawler interrupted")
+
+// watchSignals interrupts the crawler on SIGINT or SIGTERM. The returned func stops watching.
+// After the first signal the default handling is restored, so a second signal kills the process right away.
+func (app *Crawler) watchSignals() func() {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	done := make(chan struct{})
+	go func() {
+		select {
+		case sig := <-signals:
+			signal.Stop(signals)
+			app.Logger.Summary("Received %v, finishing in-flight requests within %v", sig, app.shutdownGracePeriod())
+			app.interrupt()
+		case <-done:
+		}
+	}()
+	return func() {
+		signal.Stop(signals)
+		close(done)
+	}
+}
+
+// runUntilShutdown runs fn until it returns, or until the grace period after a termination signal expired.
+func (app *Crawler) runUntilShutdown(fn func()) {
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		fn()
+	}()
+
+	select {
+	case <-done:
+		return
+	case <-app.interrupted:
+	}
+	select {
+	case <-done:
+	case <-time.After(app.shutdownGracePeriod()):
+		app.Logger.Warn("In-flight requests did not finish within %v, shutting down", app.shutdownGracePeriod())
+	}
+}
+
+// shutdownGracePeriod reads SHUTDOWN_GRACE_PERIOD (e.g. 25s, 1m).
+func (app *Crawler) shutdownGracePeriod() time.Duration {
+	value := app.Config.GetString("SHUTDOWN_GRACE_PERIOD")
+	if value == "" {
+		return defaultShutdownGracePeriod
+	}
+	gracePeriod, err := time.ParseDuration(value)
+	if err != nil {
+		app.Logger.Warn("Invalid SHUTDOWN_GRACE_PERIOD %q, using %v", value, defaultShutdownGracePeriod)
+		return defaultShutdownGracePeriod
+	}
+	return gracePeriod
+}
+
+// interrupt stops handing out new urls, urls already being crawled are finished.
+func (app *Crawler) interrupt() {
+	app.interruptOnce.Do(func() {
+		close(app.interrupted)
+	})
+}
+
+// fail shuts the crawler down like interrupt after a fatal error. The run is not marked interrupted,
+// so the next run starts over instead of resuming it.
+func (app *Crawler) fail() {
+	app.failed.Store(true)
+	app.interrupt()
+}
+
+// isFailed reports whether the crawler stopped on a fatal error.
+func (app *Crawler) isFailed() bool {
+	return app.failed.Load()
+}
+
+// isInterrupted reports whether the crawler received a termination signal.
+func (app *Crawler) isInterrupted() bool {
+	select {
+	case <-app.interrupted:
+		return true
+	default:
+		return false
+	}
+}
+
+// wasInterrupted reports whether the previous run of the site was stopped by a termination signal.
+func (app *Crawler) wasInterrupted() bool {
+	site, err := app.store.FindSite(context.Background(), app.Url)
+	return err == nil && site.Interrupted
+}
+
+// resumeSite clears the interrupted flag of the site record when a new run picks up the pending urls.
+func (app *Crawler) resumeSite() {
+	site, err := app.store.FindSite(context.Background(), app.Url)
+	if err != nil {
+		app.Logger.Error("Could not find site: %v", err)
+		return
+	}
+	if err := app.store.UpdateSite(context.Background(), app.Url, Map{"interrupted": false, "attempts": site.Attempts + 1, "ended_at": nil}); err != nil {
+		app.Logger.Error("Could not update site: %v", err)
+	}
+}
+
+// markSiteInterrupted records on the site record that the run was stopped by a termination signal.
+func (app *Crawler) markSiteInterrupted() {
+	if err := app.store.UpdateSite(context.Background(), app.Url, Map{"interrupted": true, "ended_at": time.Now()}); err != nil {
+		app.Logger.Error("Could not mark site as interrupted: %v", err)
+	}
+}
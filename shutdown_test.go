@@ -0,0 +1,83 @@
+package ninjacrawler
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestFatalBootstrapErrorIsNotResumed(t *testing.T) {
+	site := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/robots.txt" {
+			fmt.Fprint(w, "User-agent: *\nDisallow: /\n")
+		}
+	}))
+	defer site.Close()
+
+	tests := []struct {
+		name       string
+		engine     Engine
+		preference AppPreference
+	}{
+		{"robots", Engine{}, AppPreference{CheckRobotsTxt: Bool(true)}},
+		// No Playwright driver is installed in the temp directory, so the browser client fails to start
+		{"browser", Engine{IsDynamic: Bool(true)}, AppPreference{}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			app := newTestCrawler(t, "fatal_"+test.name, site.URL, test.engine)
+			app.SetPreference(test.preference)
+			t.Setenv("PLAYWRIGHT_DRIVER_PATH", t.TempDir())
+			ran := false
+			app.Handle(Handler{UrlHandler: func(*Crawler) { ran = true }})
+
+			if !app.isFailed() {
+				t.Fatal("crawler did not fail")
+			}
+			if ran {
+				t.Error("handlers ran after a fatal bootstrap error")
+			}
+			// The next run of the site starts over instead of resuming the failed one
+			next := NewCrawler(app.Name, site.URL, test.engine)
+			defer next.store.Close()
+			if next.wasInterrupted() {
+				t.Error("failed run was marked interrupted")
+			}
+			_, err := next.store.FindSite(context.Background(), site.URL)
+			if created := err == nil; created != (test.name == "browser") {
+				t.Errorf("site record created = %v, the robots.txt check must stop Start before it", created)
+			}
+		})
+	}
+}
+
+func TestStopWhileWorkersRun(t *testing.T) {
+	app := newTestCrawler(t, "stop_workers", "http://example.test")
+	app.Config.Add("MONITOR_ADDR", "127.0.0.1:0")
+	app.Config.Add("TRACING_EXPORTER", TracingExporterStdout)
+	app.startMonitorPanel()
+	app.startTracing()
+
+	// A worker outliving the shutdown grace period keeps tracking and tracing while Stop runs
+	done := make(chan struct{})
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		for {
+			select {
+			case <-done:
+				return
+			default:
+			}
+			app.trackProcessor(ProcessorConfig{Entity: "products"})
+			app.trackSample("products", &ProductDetail{Url: "http://example.test/1"})
+			_, _ = app.startSpan(context.Background(), "crawl")
+		}
+	}()
+	app.stopMonitorPanel()
+	app.stopTracing()
+	close(done)
+	<-stopped
+}
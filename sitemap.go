@@ -0,0 +1,170 @@
+package ninjacrawler
+
+import (
+	"bufio"
+	"compress/gzip"
+	"context"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// SitemapFilter selects the sitemap entries seeded into a collection.
+type SitemapFilter struct {
+	Patterns      []string  // Regular expressions, an entry must match at least one of them when set
+	ModifiedSince time.Time // Entries with an older lastmod are skipped, entries without lastmod are kept
+}
+
+type sitemapDocument struct {
+	Urls     []sitemapEntry `xml:"url"`
+	Sitemaps []sitemapEntry `xml:"sitemap"`
+}
+
+type sitemapEntry struct {
+	Loc     string `xml:"loc"`
+	LastMod string `xml:"lastmod"`
+}
+
+var sitemapDateLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02T15:04Z07:00",
+	"2006-01-02T15:04:05",
+	"2006-01-02",
+}
+
+// SeedFromSitemap inserts the urls of a sitemap into collection and returns the number of urls found.
+// Sitemap indexes are followed and gzip compressed sitemaps are supported.
+// When sitemapURL is empty, the sitemaps listed in robots.txt are used, falling back to /sitemap.xml.
+// The lastmod of every entry is kept in the MetaData of its url collection.
+func (app *Crawler) SeedFromSitemap(collection string, sitemapURL string, filter SitemapFilter) (int, error) {
+	patterns := make([]*regexp.Regexp, 0, len(filter.Patterns))
+	for _, pattern := range filter.Patterns {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return 0, fmt.Errorf("invalid sitemap pattern %s: %w", pattern, err)
+		}
+		patterns = append(patterns, re)
+	}
+
+	queue := []string{sitemapURL}
+	if sitemapURL == "" {
+		queue = app.discoverSitemaps()
+	}
+
+	total := 0
+	visited := make(map[string]bool)
+	for len(queue) > 0 {
+		current := queue[0]
+		queue = queue[1:]
+		if visited[current] {
+			continue
+		}
+		visited[current] = true
+
+		document, err := app.fetchSitemap(current)
+		if err != nil {
+			return total, err
+		}
+		for _, sitemap := range document.Sitemaps {
+			if sitemap.Loc != "" && filter.matchLastMod(sitemap.LastMod) {
+				queue = append(queue, strings.TrimSpace(sitemap.Loc))
+			}
+		}
+
+		var urlCollections []UrlCollection
+		for _, entry := range document.Urls {
+			loc := strings.TrimSpace(entry.Loc)
+			if loc == "" || !filter.matchLastMod(entry.LastMod) || !matchAny(patterns, loc) {
+				continue
+			}
+			urlCollection := UrlCollection{Url: loc}
+			if entry.LastMod != "" {
+				urlCollection.MetaData = map[string]interface{}{"lastmod": strings.TrimSpace(entry.LastMod)}
+			}
+			urlCollections = append(urlCollections, urlCollection)
+		}
+		if len(urlCollections) > 0 {
+			app.InsertUrlCollections(collection, urlCollections, current)
+			total += len(urlCollections)
+		}
+		app.Logger.Info("Seeded %d urls into %s from %s", len(urlCollections), collection, current)
+	}
+	app.Logger.Summary("[Total (%d) :%s: seeded from sitemap]", total, collection)
+	return total, nil
+}
+
+// discoverSitemaps returns the sitemaps listed in the robots.txt of the site, or the default /sitemap.xml.
+func (app *Crawler) discoverSitemaps() []string {
+	robotsData := app.getRobotsData(app.BaseUrl)
+	if robotsData != nil && len(robotsData.Sitemaps) > 0 {
+		return robotsData.Sitemaps
+	}
+	return []string{strings.TrimSuffix(app.BaseUrl, "/") + "/sitemap.xml"}
+}
+
+func (app *Crawler) fetchSitemap(sitemapURL string) (*sitemapDocument, error) {
+	if err := app.waitForRateLimit(context.Background(), sitemapURL); err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequest("GET", sitemapURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("User-Agent", app.GetUserAgent())
+
+	client := &http.Client{Timeout: app.currentEngine().Timeout}
+	response, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch sitemap %s: %w", sitemapURL, err)
+	}
+	defer response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		return nil, &HTTPStatusError{Url: sitemapURL, StatusCode: response.StatusCode, Status: response.Status}
+	}
+
+	// Detect gzip by its magic bytes, servers often send .xml.gz files without a matching Content-Type
+	var body io.Reader = bufio.NewReader(response.Body)
+	if magic, _ := body.(*bufio.Reader).Peek(2); len(magic) == 2 && magic[0] == 0x1f && magic[1] == 0x8b {
+		gzipReader, err := gzip.NewReader(body)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decompress sitemap %s: %w", sitemapURL, err)
+		}
+		defer gzipReader.Close()
+		body = gzipReader
+	}
+
+	var document sitemapDocument
+	if err := xml.NewDecoder(body).Decode(&document); err != nil {
+		return nil, fmt.Errorf("failed to parse sitemap %s: %w", sitemapURL, err)
+	}
+	return &document, nil
+}
+
+func (filter SitemapFilter) matchLastMod(lastMod string) bool {
+	if filter.ModifiedSince.IsZero() || lastMod == "" {
+		return true
+	}
+	lastMod = strings.TrimSpace(lastMod)
+	for _, layout := range sitemapDateLayouts {
+		if t, err := time.Parse(layout, lastMod); err == nil {
+			return !t.Before(filter.ModifiedSince)
+		}
+	}
+	return true
+}
+
+func matchAny(patterns []*regexp.Regexp, s string) bool {
+	if len(patterns) == 0 {
+		return true
+	}
+	for _, re := range patterns {
+		if re.MatchString(s) {
+			return true
+		}
+	}
+	return false
+}
@@ -1,29 +1,34 @@
 package ninjacrawler
 
 import (
-	"crypto/tls"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"github.com/PuerkitoBio/goquery"
+	"go.opentelemetry.io/otel/attribute"
+	semconv "go.opentelemetry.io/otel/semconv/v1.24.0"
 	"golang.org/x/net/html/charset"
 	"io"
-	"log"
-	"net"
 	"net/http"
-	"net/url"
 	"strings"
 	"time"
 )
 
 func (app *Crawler) GetHttpClient() *http.Client {
 	client := &http.Client{
-		Timeout: app.engine.Timeout,
+		Timeout: app.currentEngine().Timeout,
 	}
 	return client
 }
 
 func (app *Crawler) NavigateToStaticURL(client *http.Client, urlString string, proxyServer Proxy) (*goquery.Document, error) {
-	body, ContentType, err := app.getResponseBody(client, urlString, proxyServer, 0)
+	return app.navigateToStaticURL(context.Background(), client, urlString, proxyServer)
+}
+
+func (app *Crawler) navigateToStaticURL(ctx context.Context, client *http.Client, urlString string, proxyServer Proxy) (*goquery.Document, error) {
+	engine := app.engineFor(ctx)
+	body, ContentType, err := app.getResponseBody(ctx, client, urlString, proxyServer, 0)
 	if err != nil {
 		return nil, err
 	}
@@ -39,14 +44,14 @@ func (app *Crawler) NavigateToStaticURL(client *http.Client, urlString string, p
 		return nil, err
 	}
 
-	if app.engine.SendHtmlToBigquery != nil && *app.engine.SendHtmlToBigquery {
-		sendErr := app.SendHtmlToBigquery(document, urlString)
+	if engine.SendHtmlToBigquery != nil && *engine.SendHtmlToBigquery {
+		sendErr := app.SendHtmlToBigqueryContext(ctx, document, urlString)
 		if sendErr != nil {
 			app.Logger.Error("SendHtmlToBigquery Error: %s", sendErr.Error())
 		}
 	}
 
-	if *app.engine.StoreHtml {
+	if *engine.StoreHtml {
 		if StoreHtmlErr := app.SaveHtml(document, urlString); StoreHtmlErr != nil {
 			app.Logger.Error(StoreHtmlErr.Error())
 		}
@@ -55,7 +60,11 @@ func (app *Crawler) NavigateToStaticURL(client *http.Client, urlString string, p
 }
 
 func (app *Crawler) NavigateToApiURL(client *http.Client, urlString string, proxyServer Proxy) (map[string]interface{}, error) {
-	body, _, err := app.getResponseBody(client, urlString, proxyServer, 0)
+	return app.navigateToApiURL(context.Background(), client, urlString, proxyServer)
+}
+
+func (app *Crawler) navigateToApiURL(ctx context.Context, client *http.Client, urlString string, proxyServer Proxy) (map[string]interface{}, error) {
+	body, _, err := app.getResponseBody(ctx, client, urlString, proxyServer, 0)
 	if err != nil {
 		return nil, err
 	}
@@ -70,114 +79,124 @@ func (app *Crawler) NavigateToApiURL(client *http.Client, urlString string, prox
 	return jsonResponse, nil
 }
 
-func (app *Crawler) getResponseBody(client *http.Client, urlString string, proxyServer Proxy, attempt int) ([]byte, string, error) {
-	app.mu.Lock()         // Lock before accessing/modifying shared state
-	defer app.mu.Unlock() // Unlock when the function returns
-	app.CurrentUrl = urlString
-	ContentType := ""
-	//proxyIp := ""
-	originalUrl := urlString
-
-	httpTransport := &http.Transport{
-		DialContext: (&net.Dialer{
-			Timeout:   90 * time.Second,
-			KeepAlive: 90 * time.Second,
-		}).DialContext,
-		TLSHandshakeTimeout:   90 * time.Second,
-		ResponseHeaderTimeout: 90 * time.Second,
-		ExpectContinueTimeout: 1 * time.Second,
-		TLSClientConfig: &tls.Config{
-			InsecureSkipVerify: true,
-			MinVersion:         tls.VersionTLS12,
-		},
-	}
-
-	if app.engine.Provider == "zenrows" {
-
-		urlString = app.GetQueryEscapeFullUrl(urlString)
-		zenrowsApiKey := app.Config.EnvString("ZENROWS_API_KEY")
-		queryString := app.BuildQueryString()
-		urlString = fmt.Sprintf("https://api.zenrows.com/v1/?apikey=%s&url=%s&%s", zenrowsApiKey, urlString, queryString)
-
-	} else {
-		if len(app.engine.ProxyServers) > 0 && proxyServer.Server != "" {
-			// Ensure the proxy URL has a scheme, defaulting to http:// if absent
-			proxyServerUrl, err := ensureScheme(proxyServer.Server)
-			if err != nil {
-				log.Fatalf("Failed to ensure scheme for proxy URL: %v", err)
-			}
+func (app *Crawler) getResponseBody(ctx context.Context, client *http.Client, urlString string, proxyServer Proxy, attempt int) ([]byte, string, error) {
+	if app.useResponseCache(ctx) {
+		if cached, ok := app.readResponseCache(urlString); ok {
+			app.Logger.Debug("Serving %s from response cache", urlString)
+			return cached.Body, cached.ContentType, nil
+		}
+	}
+	if err := app.waitForRateLimit(ctx, urlString); err != nil {
+		return nil, "", err
+	}
+	engine := app.engineFor(ctx)
+	ctx, span := app.startSpan(ctx, "http_request", semconv.URLFull(urlString), attribute.String("provider", providerName(engine)))
+	req := &FetchRequest{Url: urlString, Proxy: proxyServer, Engine: engine, Header: http.Header{}, client: client}
+	app.setConditionalHeaders(ctx, req.Header, urlString)
+	resp, err := app.fetchWithEscalation(ctx, req)
+	endSpan(span, err)
+	if err != nil {
+		return nil, "", err
+	}
+	contentType := resp.Header.Get("Content-Type")
+	if app.useResponseCache(ctx) {
+		if cacheErr := app.writeResponseCache(urlString, contentType, resp.Body); cacheErr != nil {
+			app.Logger.Error("Could not write response cache: %v", cacheErr)
+		}
+	}
+	return resp.Body, contentType, nil
+}
 
-			// Parse the proxy URL
-			proxyURL, err := url.Parse(proxyServerUrl)
-			if err != nil {
-				log.Fatalf("Failed to parse proxy URL: %v", err)
-			}
-			// Set proxy credentials if available
-			if proxyServer.Username != "" && proxyServer.Password != "" {
-				proxyURL.User = url.UserPassword(proxyServer.Username, proxyServer.Password)
-			}
+// httpFetcher is the "http" provider, downloading urls with net/http through the proxy of the request.
+type httpFetcher struct {
+	app *Crawler
+}
 
-			// Set the proxy in the transport
-			httpTransport.Proxy = http.ProxyURL(proxyURL)
+func (f *httpFetcher) Fetch(ctx context.Context, req *FetchRequest) (*FetchResponse, error) {
+	return f.app.doRequest(ctx, req, req.Url)
+}
 
-			if proxyURL.Scheme == "http" || proxyURL.Scheme == "https" {
-				httpTransport.TLSClientConfig = &tls.Config{
-					InsecureSkipVerify: true,
-				}
-			}
+// zenrowsFetcher is the "zenrows" provider, downloading urls through the zenrows api with the ProviderOption of the engine.
+type zenrowsFetcher struct {
+	app *Crawler
+}
+
+func (f *zenrowsFetcher) Fetch(ctx context.Context, req *FetchRequest) (*FetchResponse, error) {
+	zenrowsApiKey := f.app.Config.EnvString("ZENROWS_API_KEY")
+	apiUrl := fmt.Sprintf("https://api.zenrows.com/v1/?apikey=%s&url=%s&%s", zenrowsApiKey, f.app.GetQueryEscapeFullUrl(req.Url), buildQueryString(req.Engine.ProviderOption))
+	// The zenrows api brings its own proxies and headers
+	return f.app.doRequest(ctx, &FetchRequest{Url: req.Url, Engine: req.Engine, client: req.client}, apiUrl)
+}
 
-			client.Transport = httpTransport
+// doRequest sends a GET request to requestUrl for req, through the proxy of req.
+func (app *Crawler) doRequest(ctx context.Context, req *FetchRequest, requestUrl string) (*FetchResponse, error) {
+	engine := req.Engine
+	proxyServer := req.Proxy
+	if len(engine.ProxyServers) == 0 {
+		proxyServer = Proxy{}
+	}
+	// The client of the caller is copied, its transport is shared by the concurrent requests through the same proxy
+	client := *req.client
+	if client.Transport == nil {
+		transport, err := app.transports.get(proxyServer, engine)
+		if err != nil {
+			return nil, err
 		}
+		client.Transport = transport
 	}
-	req, err := http.NewRequest("GET", urlString, nil)
+
+	httpReq, err := http.NewRequestWithContext(ctx, "GET", requestUrl, nil)
 	if err != nil {
-		return nil, ContentType, fmt.Errorf("Failed to create request: %v", err)
+		return nil, fmt.Errorf("Failed to create request: %v", err)
+	}
+	for key, values := range req.Header {
+		for _, value := range values {
+			httpReq.Header.Add(key, value)
+		}
 	}
 	userAgent := "Mozilla/5.0 (X11; Linux x86_64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/128.0.0.0 Safari/537.36"
 	// Overwrite the default User-Agent header
 	if app.userAgent != "" {
 		userAgent = app.GetUserAgent()
 	}
-	req.Header.Set("User-Agent", userAgent)
-	req.Header.Set("Referer", app.BaseUrl)
+	httpReq.Header.Set("User-Agent", userAgent)
+	httpReq.Header.Set("Referer", app.BaseUrl)
 
-	resp, err := client.Do(req)
+	start := time.Now()
+	resp, err := client.Do(httpReq)
 	if err != nil {
-		errMsg := fmt.Sprintf("failed to navigate %s", err.Error())
-		if strings.Contains(err.Error(), "Client.Timeout") {
-			_ = app.updateStatusCode(originalUrl, 408)
-			return nil, ContentType, fmt.Errorf(errMsg)
+		if isTimeout(err) {
+			_ = app.updateStatusCode(ctx, req.Url, 408)
+			return nil, &TimeoutError{Url: req.Url, Err: err}
 		}
-		if strings.Contains(err.Error(), "Too Many Requests") {
-			if inArray(app.engine.ErrorCodes, 429) {
-				errMsg = fmt.Sprintf("isRetryable : Too Many Requests: %v", err)
+		var connectErr *HTTPStatusError
+		if errors.As(err, &connectErr) && connectErr.StatusCode == http.StatusTooManyRequests {
+			_ = app.updateStatusCode(ctx, req.Url, http.StatusTooManyRequests)
+			var statusErr error = &HTTPStatusError{Url: req.Url, StatusCode: connectErr.StatusCode, Status: connectErr.Status}
+			if inArray(engine.ErrorCodes, http.StatusTooManyRequests) {
+				statusErr = &BlockedError{Url: req.Url, Err: statusErr}
 			}
-			_ = app.updateStatusCode(originalUrl, 429)
-			return nil, ContentType, fmt.Errorf(errMsg)
+			return nil, statusErr
 		}
-		_, e := app.handleProxyError(proxyServer, err)
-		return nil, ContentType, e
+		_, e := app.handleProxyError(ctx, proxyServer, err)
+		return nil, e
 	}
 	defer resp.Body.Close()
 
 	body, err := io.ReadAll(resp.Body)
 	if err != nil {
-		return nil, ContentType, fmt.Errorf("failed to read response body: %w", err)
-	}
-	ContentType = resp.Header.Get("Content-Type")
-	_ = app.updateStatusCode(originalUrl, resp.StatusCode)
-	// Check if a redirect occurred
-	if req.URL.String() != resp.Request.URL.String() && *app.engine.TrackRedirection {
-		resUrl, _ := url.Parse(resp.Request.URL.String())
-		if resUrl.Host != req.Host {
-			finalURL := resp.Request.URL.String()
-			app.CurrentUrl = finalURL
-			_ = app.updateRedirection(originalUrl, finalURL)
-			app.Logger.Warn(fmt.Sprintf("Redirection detected: %s -> %s", originalUrl, finalURL))
-		}
-	}
-	if resp.StatusCode != http.StatusOK {
-		return nil, ContentType, app.handleHttpError(resp.StatusCode, resp.Status, originalUrl, body)
-	}
-	return body, ContentType, nil
+		return nil, fmt.Errorf("failed to read response body: %w", err)
+	}
+	finalUrl := req.Url
+	if httpReq.URL.String() != resp.Request.URL.String() {
+		finalUrl = resp.Request.URL.String()
+	}
+	return &FetchResponse{
+		Body:       body,
+		StatusCode: resp.StatusCode,
+		Status:     resp.Status,
+		Header:     resp.Header,
+		FinalUrl:   finalUrl,
+		Duration:   time.Since(start),
+	}, nil
 }
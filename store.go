@@ -0,0 +1,107 @@
+package ninjacrawler
+
+import (
+	"context"
+	"fmt"
+)
+
+const (
+	StoreDriverMongo     = "mongo"
+	StoreDriverBolt      = "bolt"
+	StoreDriverDatastore = "datastore"
+)
+
+// Store is the persistence backend of the crawler.
+// It holds the url collections, product details and site records of a single site.
+// Every call but Close takes the context of the crawl, so cancelling a crawl also cancels its database calls.
+type Store interface {
+	// InsertUrlCollections inserts url collections, silently skipping duplicates in unique collections.
+	InsertUrlCollections(ctx context.Context, collection string, urlCollections []UrlCollection) error
+	// FindUrlCollections returns url collections matching the filter.
+	FindUrlCollections(ctx context.Context, collection string, filter UrlFilter) ([]UrlCollection, error)
+	// FindUrlCollection returns the url collection stored for url.
+	FindUrlCollection(ctx context.Context, collection string, url string) (*UrlCollection, error)
+	// UpdateUrlCollection sets the given fields (keyed by their bson names) on the document stored for url.
+	UpdateUrlCollection(ctx context.Context, collection string, url string, fields Map) error
+	// CountUrlCollections counts the documents matching the filter.
+	CountUrlCollections(ctx context.Context, collection string, filter UrlFilter) (int, error)
+
+	// SaveProductDetail upserts a product detail by url, or inserts it when the collection is not unique.
+	SaveProductDetail(ctx context.Context, collection string, productDetail *ProductDetail) error
+	// FindProductDetail returns the product detail stored for url.
+	FindProductDetail(ctx context.Context, collection string, url string) (*ProductDetail, error)
+	// FindProductDetails returns a page of product details.
+	FindProductDetails(ctx context.Context, collection string, skip, limit int) ([]ProductDetail, error)
+
+	// InsertSite inserts the site record into the base collection.
+	InsertSite(ctx context.Context, site SiteCollection) error
+	// FindSite returns the site record stored for url.
+	FindSite(ctx context.Context, url string) (*SiteCollection, error)
+	// UpdateSite sets the given fields (keyed by their bson names) on the site record stored for url.
+	UpdateSite(ctx context.Context, url string, fields Map) error
+
+	// Drop removes every collection of the site.
+	Drop(ctx context.Context) error
+	Close() error
+}
+
+// UrlFilter describes which url collections a query should match.
+// Nil fields are not filtered on.
+type UrlFilter struct {
+	Status      *bool
+	Error       *bool
+	MinAttempts *int // attempts >= MinAttempts
+	MaxAttempts *int // attempts < MaxAttempts
+	Limit       int
+}
+
+func (f UrlFilter) match(urlCollection UrlCollection) bool {
+	if f.Status != nil && urlCollection.Status != *f.Status {
+		return false
+	}
+	if f.Error != nil && urlCollection.Error != *f.Error {
+		return false
+	}
+	if f.MinAttempts != nil && urlCollection.Attempts < *f.MinAttempts {
+		return false
+	}
+	if f.MaxAttempts != nil && urlCollection.Attempts >= *f.MaxAttempts {
+		return false
+	}
+	return true
+}
+
+// SetStore replaces the storage backend of the crawler.
+func (app *Crawler) SetStore(store Store) *Crawler {
+	if app.store != nil {
+		_ = app.store.Close()
+	}
+	app.store = app.instrumentStore(store)
+	return app
+}
+
+// GetStore returns the storage backend of the crawler.
+func (app *Crawler) GetStore() Store {
+	return app.store
+}
+
+// mustGetStore opens the backend selected by DB_DRIVER, defaulting to MongoDB.
+func (app *Crawler) mustGetStore() Store {
+	driver := app.Config.EnvString("DB_DRIVER", StoreDriverMongo)
+	switch driver {
+	case StoreDriverMongo, "":
+		store := newMongoStore(app)
+		app.Client = store.client
+		return store
+	case StoreDriverBolt:
+		store, err := newBoltStore(app, app.Config.EnvString("DB_PATH", fmt.Sprintf("storage/db/%s.db", app.Name)))
+		if err != nil {
+			panic(err)
+		}
+		return store
+	case StoreDriverDatastore:
+		return newDatastoreStore(app)
+	default:
+		panic(fmt.Sprintf("unsupported DB_DRIVER: %s", driver))
+	}
+}
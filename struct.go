@@ -1,6 +1,7 @@
 package ninjacrawler
 
 import (
+	"context"
 	"github.com/PuerkitoBio/goquery"
 	"github.com/go-rod/rod"
 	"github.com/playwright-community/playwright-go"
@@ -38,6 +39,7 @@ type ElementType struct {
 type AppPreference struct {
 	ExcludeUniqueUrlEntities []string
 	CheckRobotsTxt           *bool
+	StructureMonitor         *StructureMonitor // Detect site structure changes from selector hit rates, disabled when nil
 }
 type Preference struct {
 	DoNotMarkAsComplete   bool
@@ -79,10 +81,31 @@ type CrawlerContext struct {
 	RodPage       *rod.Page
 	ApiResponse   Map
 	State         Map
+	ctx           context.Context // Context of the crawl, carrying the span of the url being crawled
 }
+
+// Context returns the context of the crawl, cancelled when the crawl is cancelled.
+// Handlers pass it to their own calls to stop with the crawl.
+func (ctx CrawlerContext) Context() context.Context {
+	if ctx.ctx == nil {
+		return context.Background()
+	}
+	return ctx.ctx
+}
+
 type NavigationContext struct {
 	Document *goquery.Document
 	Response interface{}
+	ctx      context.Context
+}
+
+// Context returns the context of the navigation, carrying its sticky proxy session.
+// Navigations chained with it go through the same proxy.
+func (ctx NavigationContext) Context() context.Context {
+	if ctx.ctx == nil {
+		return context.Background()
+	}
+	return ctx.ctx
 }
 
 // Struct to hold both results and the UrlCollection
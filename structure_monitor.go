@@ -0,0 +1,187 @@
+package ninjacrawler
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+)
+
+// ErrStructureChanged stops a processor when StructureMonitor.StopOnChange is set and a structure change is detected.
+var ErrStructureChanged = errors.New("site structure changed")
+
+// StructureMonitor detects site structure changes by comparing the selector hit rates of a run with the previous runs.
+// Field rates are the share of pages where a ProductDetailSelector field was filled,
+// selector rates are the average number of urls a UrlSelector yields per page.
+type StructureMonitor struct {
+	DropThreshold float64 // Relative drop which raises an alert, defaults to 0.5 (rate halved)
+	MinSamples    int     // Pages needed before rates are compared, defaults to 50
+	StopOnChange  bool    // Stop the processor early once a change is detected
+	BaselinePath  string  // Defaults to storage/baselines/<site>.json
+}
+
+// StructureAlert describes a metric whose rate dropped below its baseline.
+type StructureAlert struct {
+	Entity       string  `json:"entity"`
+	Metric       string  `json:"metric"`
+	BaselineRate float64 `json:"baseline_rate"`
+	CurrentRate  float64 `json:"current_rate"`
+	Samples      int64   `json:"samples"`
+}
+
+type structureStats struct {
+	pages  int64
+	totals map[string]float64
+}
+
+// structureBaseline holds the rates of the last healthy run, per entity and metric.
+type structureBaseline map[string]map[string]float64
+
+type structureMonitor struct {
+	mu       sync.Mutex
+	config   StructureMonitor
+	stats    map[string]*structureStats
+	baseline structureBaseline
+	stopped  map[string]bool
+}
+
+func newStructureMonitor(config StructureMonitor, siteName string) *structureMonitor {
+	if config.DropThreshold <= 0 {
+		config.DropThreshold = 0.5
+	}
+	if config.MinSamples <= 0 {
+		config.MinSamples = 50
+	}
+	if config.BaselinePath == "" {
+		config.BaselinePath = filepath.Join("storage", "baselines", siteName+".json")
+	}
+	monitor := &structureMonitor{
+		config:   config,
+		stats:    make(map[string]*structureStats),
+		baseline: structureBaseline{},
+		stopped:  make(map[string]bool),
+	}
+	if data, err := os.ReadFile(config.BaselinePath); err == nil {
+		_ = json.Unmarshal(data, &monitor.baseline)
+	}
+	return monitor
+}
+
+// observe records the metrics of one page and reports whether a change was detected which should stop the processor.
+func (m *structureMonitor) observe(entity string, metrics map[string]float64) ([]StructureAlert, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	stats, ok := m.stats[entity]
+	if !ok {
+		stats = &structureStats{totals: make(map[string]float64)}
+		m.stats[entity] = stats
+	}
+	stats.pages++
+	for metric, value := range metrics {
+		stats.totals[metric] += value
+	}
+
+	if !m.config.StopOnChange || m.stopped[entity] || stats.pages%int64(m.config.MinSamples) != 0 {
+		return nil, false
+	}
+	alerts := m.compare(entity)
+	if len(alerts) > 0 {
+		m.stopped[entity] = true
+		return alerts, true
+	}
+	return nil, false
+}
+
+// compare returns the metrics of entity which dropped below their baseline. Callers must hold mu.
+func (m *structureMonitor) compare(entity string) []StructureAlert {
+	stats, ok := m.stats[entity]
+	if !ok || stats.pages < int64(m.config.MinSamples) {
+		return nil
+	}
+	var alerts []StructureAlert
+	for metric, baselineRate := range m.baseline[entity] {
+		currentRate := stats.totals[metric] / float64(stats.pages)
+		if baselineRate > 0 && currentRate < baselineRate*(1-m.config.DropThreshold) {
+			alerts = append(alerts, StructureAlert{
+				Entity:       entity,
+				Metric:       metric,
+				BaselineRate: baselineRate,
+				CurrentRate:  currentRate,
+				Samples:      stats.pages,
+			})
+		}
+	}
+	sort.Slice(alerts, func(i, j int) bool { return alerts[i].Metric < alerts[j].Metric })
+	return alerts
+}
+
+// finish compares the rates of entity with the baseline. Without alerts, the rates become the new baseline.
+func (m *structureMonitor) finish(entity string) ([]StructureAlert, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	alerts := m.compare(entity)
+	stats, ok := m.stats[entity]
+	if len(alerts) > 0 || !ok || stats.pages < int64(m.config.MinSamples) {
+		return alerts, nil
+	}
+
+	rates := make(map[string]float64, len(stats.totals))
+	for metric, total := range stats.totals {
+		rates[metric] = total / float64(stats.pages)
+	}
+	m.baseline[entity] = rates
+	data, err := json.MarshalIndent(m.baseline, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(filepath.Dir(m.config.BaselinePath), 0755); err != nil {
+		return nil, err
+	}
+	return nil, os.WriteFile(m.config.BaselinePath, data, 0644)
+}
+
+func (m *structureMonitor) isStopped(entity string) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.stopped[entity]
+}
+
+// observeStructure records the selector hit rates of one page of the current processor.
+func (app *Crawler) observeStructure(metrics map[string]float64) {
+	if app.structure == nil || len(metrics) == 0 {
+		return
+	}
+	entity := app.currentProcessor().Entity
+	alerts, stop := app.structure.observe(entity, metrics)
+	if stop {
+		app.logStructureAlerts(alerts)
+		app.Logger.Summary("[%s] Stopping early: %v", entity, ErrStructureChanged)
+	}
+}
+
+// structureChanged reports whether the processor of entity was stopped because of a structure change.
+func (app *Crawler) structureChanged(entity string) bool {
+	return app.structure != nil && app.structure.isStopped(entity)
+}
+
+// checkStructure compares the rates of entity with the baseline at the end of a processor.
+func (app *Crawler) checkStructure(entity string) []StructureAlert {
+	if app.structure == nil {
+		return nil
+	}
+	alerts, err := app.structure.finish(entity)
+	if err != nil {
+		app.Logger.Error("Could not store structure baseline: %v", err)
+	}
+	app.logStructureAlerts(alerts)
+	return alerts
+}
+
+func (app *Crawler) logStructureAlerts(alerts []StructureAlert) {
+	for _, alert := range alerts {
+		payload, _ := json.Marshal(alert)
+		app.Logger.Summary("Site structure change detected: %s", payload)
+	}
+}
@@ -0,0 +1,99 @@
+package ninjacrawler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.24.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	TracingExporterOtlp   = "otlp"
+	TracingExporterStdout = "stdout"
+)
+
+const tracerName = "github.com/lazuli-inc/ninjacrawler"
+
+// startTracing sets up the span exporter selected by TRACING_EXPORTER.
+// otlp exports over http to TRACING_ENDPOINT, or to the standard OTEL_EXPORTER_OTLP_* variables when it is empty.
+// stdout prints the spans, which is handy in development. Tracing is disabled when TRACING_EXPORTER is empty.
+func (app *Crawler) startTracing() {
+	var (
+		exporter sdktrace.SpanExporter
+		err      error
+	)
+	switch driver := app.Config.GetString("TRACING_EXPORTER"); driver {
+	case "":
+		return
+	case TracingExporterOtlp:
+		var opts []otlptracehttp.Option
+		if endpoint := app.Config.GetString("TRACING_ENDPOINT"); endpoint != "" {
+			opts = append(opts, otlptracehttp.WithEndpointURL(endpoint))
+		}
+		exporter, err = otlptracehttp.New(context.Background(), opts...)
+	case TracingExporterStdout:
+		exporter, err = stdouttrace.New(stdouttrace.WithWriter(os.Stdout), stdouttrace.WithPrettyPrint())
+	default:
+		err = fmt.Errorf("unsupported TRACING_EXPORTER: %s", driver)
+	}
+	if err != nil {
+		app.Logger.Error("Failed to start tracing: %v", err)
+		return
+	}
+
+	app.tracerProvider = sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(resource.NewSchemaless(
+			semconv.ServiceName("ninjacrawler"),
+			attribute.String("site", app.Name),
+		)),
+	)
+	app.tracer = app.tracerProvider.Tracer(tracerName)
+}
+
+// stopTracing flushes the spans which were not exported yet. The tracer is kept, the spans of workers
+// still running after the shutdown grace period are dropped by the stopped provider.
+func (app *Crawler) stopTracing() {
+	if app.tracerProvider == nil {
+		return
+	}
+	if err := app.tracerProvider.Shutdown(context.Background()); err != nil {
+		app.Logger.Error("Failed to stop tracing: %v", err)
+	}
+}
+
+// startSpan starts a span of a crawl stage. Without TRACING_EXPORTER the span is a no-op.
+func (app *Crawler) startSpan(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	return app.tracer.Start(ctx, name, trace.WithAttributes(attrs...))
+}
+
+// endSpan records err on span and ends it. A 304 answer to a conditional request is not recorded as an error.
+func endSpan(span trace.Span, err error) {
+	if err != nil && !isNotModified(err) {
+		var statusErr *HTTPStatusError
+		if errors.As(err, &statusErr) {
+			span.SetAttributes(semconv.HTTPResponseStatusCode(statusErr.StatusCode))
+		}
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// urlAttributes returns the attributes shared by the spans of a url.
+func urlAttributes(url string, collection string, proxy Proxy) []attribute.KeyValue {
+	return []attribute.KeyValue{
+		semconv.URLFull(url),
+		attribute.String("collection", collection),
+		attribute.String("proxy", proxy.Server),
+	}
+}
@@ -0,0 +1,92 @@
+package ninjacrawler
+
+import (
+	"context"
+	"crypto/tls"
+	"net"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+)
+
+// transportKey identifies the transports which can share connections: same proxy and same connection settings.
+type transportKey struct {
+	proxy               string // Proxy url with its credentials, empty for direct connections
+	insecureSkipVerify  bool
+	maxConnsPerHost     int
+	maxIdleConnsPerHost int
+}
+
+// transportPool keeps a long-lived transport per proxy, so static fetches reuse their connections with keep-alive and HTTP/2.
+type transportPool struct {
+	mu         sync.Mutex
+	transports map[transportKey]*http.Transport
+}
+
+func newTransportPool() *transportPool {
+	return &transportPool{transports: make(map[transportKey]*http.Transport)}
+}
+
+// get returns the transport of proxy with the connection settings of engine, creating it on first use.
+func (p *transportPool) get(proxy Proxy, engine *Engine) (*http.Transport, error) {
+	key := transportKey{
+		insecureSkipVerify:  *engine.InsecureSkipVerify,
+		maxConnsPerHost:     engine.MaxConnsPerHost,
+		maxIdleConnsPerHost: engine.MaxIdleConnsPerHost,
+	}
+	var proxyFunc func(*http.Request) (*url.URL, error)
+	if proxy.Server != "" {
+		// Parse the proxy URL with its credentials, http:// when the scheme is absent, socks5:// and socks5h:// are dialed by the transport
+		proxyUrl, err := proxyURL(proxy)
+		if err != nil {
+			return nil, &ProxyError{Proxy: proxy, Err: err}
+		}
+		key.proxy = proxyUrl.String()
+		proxyFunc = http.ProxyURL(proxyUrl)
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if transport, ok := p.transports[key]; ok {
+		return transport, nil
+	}
+	transport := &http.Transport{
+		Proxy:                  proxyFunc,
+		OnProxyConnectResponse: proxyConnectError,
+		DialContext: (&net.Dialer{
+			Timeout:   90 * time.Second,
+			KeepAlive: 90 * time.Second,
+		}).DialContext,
+		ForceAttemptHTTP2:     true, // A custom dialer and tls config disable HTTP/2 otherwise
+		MaxIdleConnsPerHost:   key.maxIdleConnsPerHost,
+		MaxConnsPerHost:       key.maxConnsPerHost,
+		IdleConnTimeout:       90 * time.Second,
+		TLSHandshakeTimeout:   90 * time.Second,
+		ResponseHeaderTimeout: 90 * time.Second,
+		ExpectContinueTimeout: 1 * time.Second,
+		TLSClientConfig: &tls.Config{
+			InsecureSkipVerify: key.insecureSkipVerify,
+			MinVersion:         tls.VersionTLS12,
+		},
+	}
+	p.transports[key] = transport
+	return transport, nil
+}
+
+// proxyConnectError returns an HTTPStatusError when the proxy refuses the CONNECT of an https request, e.g. with 429.
+func proxyConnectError(_ context.Context, _ *url.URL, connectReq *http.Request, connectRes *http.Response) error {
+	if connectRes.StatusCode == http.StatusOK {
+		return nil
+	}
+	return &HTTPStatusError{Url: connectReq.URL.String(), StatusCode: connectRes.StatusCode, Status: connectRes.Status}
+}
+
+// closeIdle closes the idle connections of every transport.
+func (p *transportPool) closeIdle() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for _, transport := range p.transports {
+		transport.CloseIdleConnections()
+	}
+}
@@ -11,9 +11,14 @@ import (
 // Use Crawl instead, which includes improvements for proxy rotation and error handling.
 func (app *Crawler) CrawlUrls(processorConfigs []ProcessorConfig) {
 	for _, processorConfig := range processorConfigs {
+		if app.isInterrupted() {
+			break
+		}
 		app.Logger.Summary("Starting :%s: Crawler", processorConfig.OriginCollection)
-		app.overrideEngineDefaults(app.engine, &processorConfig.Engine)
+		app.engine.Store(app.resolveEngine(&processorConfig.Engine))
+		app.warnInsecureEngine(processorConfig.OriginCollection, app.currentEngine())
 		app.toggleClient()
+		app.trackProcessor(processorConfig)
 		processedUrls := make(map[string]bool) // Track processed URLs
 		total := int32(0)
 		app.crawlUrlsRecursive(processorConfig, processedUrls, &total, 0)
@@ -25,17 +30,21 @@ func (app *Crawler) CrawlUrls(processorConfigs []ProcessorConfig) {
 
 		errDataCount := app.GetErrorDataCount(processorConfig.OriginCollection)
 		if errDataCount > 0 {
-			app.Logger.Summary("Error count: %s", errDataCount)
+			app.Logger.Summary("Error count: %d", errDataCount)
 		}
+		app.checkStructure(processorConfig.Entity)
 	}
 }
 func (app *Crawler) crawlUrlsRecursive(processorConfig ProcessorConfig, processedUrls map[string]bool, total *int32, counter int32) {
 	for {
-		productListData := app.getUrlCollections(processorConfig.OriginCollection)
+		productListData := app.getUrlCollections(context.Background(), processorConfig.OriginCollection)
 
 		if len(productListData) == 0 {
 			return // Exit recursion if no data to process
 		}
+		if app.structureChanged(processorConfig.Entity) || app.isInterrupted() {
+			return
+		}
 
 		var wg sync.WaitGroup
 
@@ -57,7 +66,7 @@ func (app *Crawler) handleJob(urlCollections []UrlCollection, processorConfig Pr
 	defer wg.Done()
 
 	// Set a timeout for the context to prevent infinite waiting.
-	timeoutDuration := time.Duration(app.engine.CrawlTimeout) * time.Second
+	timeoutDuration := time.Duration(app.currentEngine().CrawlTimeout) * time.Second
 	ctx, cancel := context.WithTimeout(context.Background(), timeoutDuration)
 	defer cancel()
 
@@ -67,7 +76,7 @@ func (app *Crawler) handleJob(urlCollections []UrlCollection, processorConfig Pr
 	// Initialize the URL channel with the provided URLs
 	for _, urlCollection := range urlCollections {
 		urlChan <- urlCollection
-		if urlCollection.Attempts > 0 && urlCollection.Attempts <= app.engine.MaxRetryAttempts {
+		if urlCollection.Attempts > 0 && urlCollection.Attempts <= app.currentEngine().MaxRetryAttempts {
 			processedUrls[urlCollection.CurrentPageUrl] = false // Do not mark URL as processed
 			processedUrls[urlCollection.Url] = false            // Do not mark URL as processed
 		} else {
@@ -77,11 +86,11 @@ func (app *Crawler) handleJob(urlCollections []UrlCollection, processorConfig Pr
 	}
 	close(urlChan)
 
-	proxyCount := len(app.engine.ProxyServers)
-	batchSize := app.engine.ConcurrentLimit
+	proxyCount := len(app.currentEngine().ProxyServers)
+	batchSize := app.currentEngine().ConcurrentLimit
 	totalUrls := len(urlCollections)
 	goroutineCount := min(max(proxyCount, 1)*batchSize, totalUrls)
-	if app.engine.ProxyStrategy == ProxyStrategyRotation {
+	if app.currentEngine().ProxyStrategy == ProxyStrategyRotation {
 		goroutineCount = min(batchSize, totalUrls)
 	}
 
@@ -91,7 +100,7 @@ func (app *Crawler) handleJob(urlCollections []UrlCollection, processorConfig Pr
 		proxy := Proxy{}
 		currentProxyIndex := int32(0)
 		if proxyCount > 0 {
-			proxy = app.engine.ProxyServers[i%proxyCount]
+			proxy = app.currentEngine().ProxyServers[i%proxyCount]
 			currentProxyIndex = int32(i % proxyCount)
 		}
 		innerWg.Add(1)
@@ -102,7 +111,6 @@ func (app *Crawler) handleJob(urlCollections []UrlCollection, processorConfig Pr
 					app.HandlePanic(r)
 				}
 			}()
-			app.CurrentCollection = processorConfig.OriginCollection
 			app.crawlWorker(ctx, processorConfig, urlChan, resultChan, app.isLocalEnv, &counter, &currentProxyIndex)
 		}(proxy)
 	}
@@ -126,7 +134,7 @@ func (app *Crawler) handleJob(urlCollections []UrlCollection, processorConfig Pr
 	}
 
 	// Cancel the context if the dev crawl limit is reached
-	if app.isLocalEnv && atomic.LoadInt32(&counter) >= int32(app.engine.DevCrawlLimit) {
+	if app.isLocalEnv && atomic.LoadInt32(&counter) >= int32(app.currentEngine().DevCrawlLimit) {
 		app.Logger.Warn("Dev Crawl limit reached. Cancelling job...")
 		cancel()
 	}